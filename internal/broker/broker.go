@@ -0,0 +1,205 @@
+// Package broker carries signaling traffic between server instances and keeps
+// cluster-wide presence: which connection identities are in which room and
+// which node holds them.
+package broker
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Member is one signaling connection registered in a room. Identity is
+// unique per connection; a user with several devices has one member each,
+// sharing User and UID.
+type Member struct {
+	Identity string `json:"identity"`
+	User     string `json:"user,omitempty"`
+	Device   string `json:"device,omitempty"`
+	UID      uint   `json:"uid"`
+	Node     string `json:"node"`
+}
+
+// Hand is a raised hand in a room's queue. An account (UID) has at most one,
+// whatever the number of devices or the identity each connected with; User
+// is the identity it was raised with.
+type Hand struct {
+	User     string `json:"user"`
+	UID      uint   `json:"uid"`
+	RaisedAt int64  `json:"raised_at"`
+}
+
+// Broker is implemented by the in-memory (single node) and Redis backends.
+type Broker interface {
+	// Node identifies this server instance.
+	Node() string
+	// Publish hands payload to the subscriber of every node, this one
+	// included. Payloads from one node arrive in the order they were sent.
+	Publish(ctx context.Context, payload []byte) error
+	// Subscribe registers the handler for published payloads. It is called
+	// once, before the node starts serving.
+	Subscribe(handler func(payload []byte)) error
+	// Claim registers m in room on this node. It returns false when the
+	// identity is already held in the room by a live node.
+	Claim(ctx context.Context, room string, m Member) (bool, error)
+	// Release removes the room's entry for identity if this node holds it.
+	Release(ctx context.Context, room, identity string) error
+	// Members lists the members of the room held by live nodes.
+	Members(ctx context.Context, room string) ([]Member, error)
+	// Rooms lists the members of every non-empty room.
+	Rooms(ctx context.Context) (map[string][]Member, error)
+	// RaiseHand appends the account's hand to the room's queue. It returns
+	// false when the hand is already up.
+	RaiseHand(ctx context.Context, room string, hand Hand) (bool, error)
+	// LowerHands takes down the hands of the given accounts, or every hand
+	// of the room when none is given, and returns the hands that were up.
+	LowerHands(ctx context.Context, room string, uids ...uint) ([]Hand, error)
+	// Hands lists the room's raised hands in the order they went up.
+	Hands(ctx context.Context, room string) ([]Hand, error)
+	// Lease takes the named lease for this node, or renews it if this node
+	// already holds it, for ttl. It reports whether this node holds it now;
+	// at most one live node does.
+	Lease(ctx context.Context, name string, ttl time.Duration) (bool, error)
+	Close() error
+}
+
+// Memory keeps everything in process; it is the default for a single node.
+type Memory struct {
+	mu      sync.RWMutex
+	rooms   map[string]map[string]Member
+	hands   map[string][]Hand
+	handler func([]byte)
+}
+
+// MemoryNode is the node name of the in-memory broker.
+const MemoryNode = "local"
+
+func NewMemory() *Memory {
+	return &Memory{
+		rooms: make(map[string]map[string]Member),
+		hands: make(map[string][]Hand),
+	}
+}
+
+func (m *Memory) Node() string { return MemoryNode }
+
+// Publish calls the handler synchronously on the caller's goroutine.
+func (m *Memory) Publish(_ context.Context, payload []byte) error {
+	m.mu.RLock()
+	handler := m.handler
+	m.mu.RUnlock()
+	if handler != nil {
+		handler(payload)
+	}
+	return nil
+}
+
+func (m *Memory) Subscribe(handler func([]byte)) error {
+	m.mu.Lock()
+	m.handler = handler
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *Memory) Claim(_ context.Context, room string, member Member) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	members, ok := m.rooms[room]
+	if !ok {
+		members = make(map[string]Member)
+		m.rooms[room] = members
+	}
+	if _, exists := members[member.Identity]; exists {
+		return false, nil
+	}
+	member.Node = MemoryNode
+	members[member.Identity] = member
+	return true, nil
+}
+
+func (m *Memory) Release(_ context.Context, room, identity string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.rooms[room], identity)
+	if len(m.rooms[room]) == 0 {
+		delete(m.rooms, room)
+	}
+	return nil
+}
+
+func (m *Memory) Members(_ context.Context, room string) ([]Member, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return sortedMembers(m.rooms[room]), nil
+}
+
+func (m *Memory) Rooms(_ context.Context) (map[string][]Member, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rooms := make(map[string][]Member, len(m.rooms))
+	for room, members := range m.rooms {
+		rooms[room] = sortedMembers(members)
+	}
+	return rooms, nil
+}
+
+func (m *Memory) RaiseHand(_ context.Context, room string, hand Hand) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, h := range m.hands[room] {
+		if h.UID == hand.UID {
+			return false, nil
+		}
+	}
+	m.hands[room] = append(m.hands[room], hand)
+	return true, nil
+}
+
+func (m *Memory) LowerHands(_ context.Context, room string, uids ...uint) ([]Hand, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lower := make(map[uint]bool, len(uids))
+	for _, uid := range uids {
+		lower[uid] = true
+	}
+	var lowered []Hand
+	kept := make([]Hand, 0, len(m.hands[room]))
+	for _, h := range m.hands[room] {
+		if len(uids) == 0 || lower[h.UID] {
+			lowered = append(lowered, h)
+			continue
+		}
+		kept = append(kept, h)
+	}
+	if len(kept) == 0 {
+		delete(m.hands, room)
+	} else {
+		m.hands[room] = kept
+	}
+	return lowered, nil
+}
+
+func (m *Memory) Hands(_ context.Context, room string) ([]Hand, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	hands := make([]Hand, len(m.hands[room]))
+	copy(hands, m.hands[room])
+	return hands, nil
+}
+
+// Lease always succeeds: a single node holds every lease.
+func (m *Memory) Lease(_ context.Context, _ string, _ time.Duration) (bool, error) {
+	return true, nil
+}
+
+func (m *Memory) Close() error { return nil }
+
+func sortedMembers(members map[string]Member) []Member {
+	list := make([]Member, 0, len(members))
+	for _, member := range members {
+		list = append(list, member)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Identity < list[j].Identity })
+	return list
+}
@@ -0,0 +1,182 @@
+package broker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedis(t *testing.T, srv *miniredis.Miniredis, node string) *Redis {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+	b, err := NewRedis(client, "test:", node)
+	if err != nil {
+		t.Fatalf("NewRedis(%s): %v", node, err)
+	}
+	t.Cleanup(func() {
+		_ = b.Close()
+		_ = client.Close()
+	})
+	return b
+}
+
+func TestMemoryPresence(t *testing.T) {
+	ctx := context.Background()
+	b := NewMemory()
+	if ok, _ := b.Claim(ctx, "room", Member{Identity: "alice", UID: 1}); !ok {
+		t.Fatal("first claim failed")
+	}
+	if ok, _ := b.Claim(ctx, "room", Member{Identity: "alice", UID: 1}); ok {
+		t.Fatal("duplicate claim succeeded")
+	}
+	_ = b.Release(ctx, "room", "alice")
+	if members, _ := b.Members(ctx, "room"); len(members) != 0 {
+		t.Fatalf("members after release = %v", members)
+	}
+}
+
+func TestRedisPresenceAcrossNodes(t *testing.T) {
+	ctx := context.Background()
+	srv := miniredis.RunT(t)
+	a := newTestRedis(t, srv, "a")
+	b := newTestRedis(t, srv, "b")
+
+	if ok, err := a.Claim(ctx, "room", Member{Identity: "alice", UID: 1}); err != nil || !ok {
+		t.Fatalf("claim on a = %v, %v", ok, err)
+	}
+	if ok, err := b.Claim(ctx, "room", Member{Identity: "alice", UID: 1}); err != nil || ok {
+		t.Fatalf("duplicate claim on b = %v, %v", ok, err)
+	}
+	if ok, _ := b.Claim(ctx, "room", Member{Identity: "bob", UID: 2}); !ok {
+		t.Fatal("claim of bob on b failed")
+	}
+
+	members, err := a.Members(ctx, "room")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 2 || members[0].Node != "a" || members[1].Node != "b" {
+		t.Fatalf("members = %+v", members)
+	}
+
+	// b may not release a's member.
+	_ = b.Release(ctx, "room", "alice")
+	if members, _ := b.Members(ctx, "room"); len(members) != 2 {
+		t.Fatalf("members after foreign release = %+v", members)
+	}
+
+	rooms, err := b.Rooms(ctx)
+	if err != nil || len(rooms["room"]) != 2 {
+		t.Fatalf("rooms = %v, %v", rooms, err)
+	}
+	// Every key shares one hash tag, for Redis Cluster.
+	if !srv.Exists("{test:}room:room") || !srv.Exists("{test:}node:a") {
+		t.Fatalf("keys are not hash-tagged: %v", srv.Keys())
+	}
+}
+
+func TestRedisDeadNodeReleasesPresence(t *testing.T) {
+	ctx := context.Background()
+	srv := miniredis.RunT(t)
+	a := newTestRedis(t, srv, "a")
+	b := newTestRedis(t, srv, "b")
+
+	if ok, _ := b.Claim(ctx, "room", Member{Identity: "alice", UID: 1}); !ok {
+		t.Fatal("claim on b failed")
+	}
+	_ = b.Close()
+
+	if members, _ := a.Members(ctx, "room"); len(members) != 0 {
+		t.Fatalf("members of a dead node are listed: %+v", members)
+	}
+	if ok, _ := a.Claim(ctx, "room", Member{Identity: "alice", UID: 1}); !ok {
+		t.Fatal("identity held by a dead node could not be claimed")
+	}
+}
+
+func TestRedisPublishReachesEveryNode(t *testing.T) {
+	ctx := context.Background()
+	srv := miniredis.RunT(t)
+	a := newTestRedis(t, srv, "a")
+	b := newTestRedis(t, srv, "b")
+
+	got := make(chan string, 4)
+	for _, n := range []*Redis{a, b} {
+		node := n.Node()
+		if err := n.Subscribe(func(p []byte) { got <- node + ":" + string(p) }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.Publish(ctx, []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	seen := make(map[string]bool)
+	for len(seen) < 2 {
+		select {
+		case msg := <-got:
+			seen[msg] = true
+		case <-time.After(2 * time.Second):
+			t.Fatalf("only received %v", seen)
+		}
+	}
+	if !seen["a:hello"] || !seen["b:hello"] {
+		t.Fatalf("received %v", seen)
+	}
+}
+
+func TestRedisHandQueueAcrossNodes(t *testing.T) {
+	ctx := context.Background()
+	srv := miniredis.RunT(t)
+	a := newTestRedis(t, srv, "a")
+	b := newTestRedis(t, srv, "b")
+
+	if ok, err := a.RaiseHand(ctx, "room", Hand{User: "bob", UID: 2, RaisedAt: 1}); err != nil || !ok {
+		t.Fatalf("raise on a = %v, %v", ok, err)
+	}
+	if ok, _ := b.RaiseHand(ctx, "room", Hand{User: "alice", UID: 1, RaisedAt: 2}); !ok {
+		t.Fatal("raise on b failed")
+	}
+	// The same account under another identity.
+	if ok, _ := b.RaiseHand(ctx, "room", Hand{User: "2", UID: 2, RaisedAt: 3}); ok {
+		t.Fatal("bob's hand went up twice")
+	}
+	hands, err := b.Hands(ctx, "room")
+	if err != nil || len(hands) != 2 || hands[0].User != "bob" || hands[1].User != "alice" {
+		t.Fatalf("hands = %+v, %v", hands, err)
+	}
+
+	if lowered, _ := b.LowerHands(ctx, "room", 2, 3); len(lowered) != 1 || lowered[0].User != "bob" {
+		t.Fatalf("lowered = %+v", lowered)
+	}
+	if lowered, _ := a.LowerHands(ctx, "room"); len(lowered) != 1 || lowered[0].UID != 1 {
+		t.Fatalf("lowered all = %+v", lowered)
+	}
+	if hands, _ := a.Hands(ctx, "room"); len(hands) != 0 {
+		t.Fatalf("hands after lowering all = %+v", hands)
+	}
+}
+
+func TestRedisLeaseHasOneHolder(t *testing.T) {
+	ctx := context.Background()
+	srv := miniredis.RunT(t)
+	a := newTestRedis(t, srv, "a")
+	b := newTestRedis(t, srv, "b")
+
+	if ok, err := a.Lease(ctx, "scheduler", time.Minute); err != nil || !ok {
+		t.Fatalf("lease on a = %v, %v", ok, err)
+	}
+	if ok, _ := b.Lease(ctx, "scheduler", time.Minute); ok {
+		t.Fatal("b took a lease held by a")
+	}
+	if ok, _ := a.Lease(ctx, "scheduler", time.Minute); !ok {
+		t.Fatal("a could not renew its lease")
+	}
+	srv.FastForward(2 * time.Minute)
+	if ok, _ := b.Lease(ctx, "scheduler", time.Minute); !ok {
+		t.Fatal("b could not take an expired lease")
+	}
+}
@@ -0,0 +1,340 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// NodeTTL is how long a node counts as alive after its last heartbeat.
+// Presence entries of a node that stopped heartbeating are ignored and
+// cleaned up lazily, so a crashed instance does not block its users.
+const NodeTTL = 15 * time.Second
+
+// Redis fans payloads out with pub/sub on one channel and stores presence
+// in one hash per room (identity -> Member JSON). Raised hands are another
+// hash per room (uid -> Hand JSON), ordered by the time they went up.
+type Redis struct {
+	client *redis.Client
+	prefix string
+	node   string
+
+	mu     sync.Mutex
+	pubsub *redis.PubSub
+	stop   context.CancelFunc
+	done   chan struct{}
+}
+
+// claimScript sets the member unless the identity is held by a live node.
+// ARGV[3] is the node key prefix; the holder's node key shares the room
+// key's hash slot (see NewRedis).
+var claimScript = redis.NewScript(`
+local cur = redis.call('HGET', KEYS[1], ARGV[1])
+if cur then
+	local node = cjson.decode(cur).node
+	if redis.call('EXISTS', ARGV[3] .. node) == 1 then
+		return 0
+	end
+end
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+return 1
+`)
+
+// releaseScript deletes the member only while the given node holds it.
+var releaseScript = redis.NewScript(`
+local cur = redis.call('HGET', KEYS[1], ARGV[1])
+if cur and cjson.decode(cur).node == ARGV[2] then
+	return redis.call('HDEL', KEYS[1], ARGV[1])
+end
+return 0
+`)
+
+// leaseScript renews the lease if the node holds it, or takes it if nobody
+// does.
+var leaseScript = redis.NewScript(`
+local cur = redis.call('GET', KEYS[1])
+if cur == ARGV[1] then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+	return 1
+end
+if cur then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+return 1
+`)
+
+// NewRedis registers node on the server and keeps it alive until Close.
+// prefix namespaces every key and the channel, e.g. "gomeetings:signal:".
+// Unless it already has one, prefix is made a hash tag ("{...}"): the claim
+// script checks node keys it cannot declare up front, so on Redis Cluster
+// every key must live in the same slot.
+func NewRedis(client *redis.Client, prefix, node string) (*Redis, error) {
+	if node == "" {
+		return nil, errors.New("broker: node name is empty")
+	}
+	if !strings.Contains(prefix, "{") {
+		prefix = "{" + prefix + "}"
+	}
+	r := &Redis{client: client, prefix: prefix, node: node, done: make(chan struct{})}
+	ctx, cancel := context.WithCancel(context.Background())
+	r.stop = cancel
+	if err := r.heartbeat(ctx); err != nil {
+		cancel()
+		return nil, err
+	}
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(NodeTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			_ = r.heartbeat(ctx)
+		}
+	}()
+	return r, nil
+}
+
+func (r *Redis) heartbeat(ctx context.Context) error {
+	return r.client.Set(ctx, r.nodeKey(r.node), 1, NodeTTL).Err()
+}
+
+func (r *Redis) channel() string             { return r.prefix + "channel" }
+func (r *Redis) roomKey(room string) string  { return r.prefix + "room:" + room }
+func (r *Redis) nodeKey(node string) string  { return r.prefix + "node:" + node }
+func (r *Redis) handsKey(room string) string { return r.prefix + "hands:" + room }
+func (r *Redis) leaseKey(name string) string { return r.prefix + "lease:" + name }
+
+func (r *Redis) Node() string { return r.node }
+
+func (r *Redis) Publish(ctx context.Context, payload []byte) error {
+	return r.client.Publish(ctx, r.channel(), payload).Err()
+}
+
+// Subscribe waits for the subscription to be confirmed, so nothing published
+// after it returns is missed, then delivers on a single goroutine.
+func (r *Redis) Subscribe(handler func([]byte)) error {
+	ctx := context.Background()
+	ps := r.client.Subscribe(ctx, r.channel())
+	if _, err := ps.Receive(ctx); err != nil {
+		_ = ps.Close()
+		return err
+	}
+	r.mu.Lock()
+	r.pubsub = ps
+	r.mu.Unlock()
+	go func() {
+		for msg := range ps.Channel() {
+			handler([]byte(msg.Payload))
+		}
+	}()
+	return nil
+}
+
+func (r *Redis) Claim(ctx context.Context, room string, m Member) (bool, error) {
+	m.Node = r.node
+	value, err := json.Marshal(m)
+	if err != nil {
+		return false, err
+	}
+	ok, err := claimScript.Run(ctx, r.client, []string{r.roomKey(room)}, m.Identity, value, r.nodeKey("")).Int()
+	if err != nil {
+		return false, err
+	}
+	return ok == 1, nil
+}
+
+func (r *Redis) Release(ctx context.Context, room, identity string) error {
+	return r.release(ctx, room, identity, r.node)
+}
+
+func (r *Redis) release(ctx context.Context, room, identity, node string) error {
+	return releaseScript.Run(ctx, r.client, []string{r.roomKey(room)}, identity, node).Err()
+}
+
+func (r *Redis) Members(ctx context.Context, room string) ([]Member, error) {
+	entries, err := r.client.HGetAll(ctx, r.roomKey(room)).Result()
+	if err != nil {
+		return nil, err
+	}
+	return r.liveMembers(ctx, room, entries)
+}
+
+func (r *Redis) Rooms(ctx context.Context) (map[string][]Member, error) {
+	rooms := make(map[string][]Member)
+	iter := r.client.Scan(ctx, 0, r.roomKey("*"), 100).Iterator()
+	for iter.Next(ctx) {
+		room := strings.TrimPrefix(iter.Val(), r.roomKey(""))
+		members, err := r.Members(ctx, room)
+		if err != nil {
+			return nil, err
+		}
+		if len(members) > 0 {
+			rooms[room] = members
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	return rooms, nil
+}
+
+func (r *Redis) RaiseHand(ctx context.Context, room string, hand Hand) (bool, error) {
+	value, err := json.Marshal(hand)
+	if err != nil {
+		return false, err
+	}
+	return r.client.HSetNX(ctx, r.handsKey(room), handField(hand.UID), value).Result()
+}
+
+func (r *Redis) LowerHands(ctx context.Context, room string, uids ...uint) ([]Hand, error) {
+	key := r.handsKey(room)
+	var entries map[string]string
+	if len(uids) == 0 {
+		all, err := r.client.HGetAll(ctx, key).Result()
+		if err != nil {
+			return nil, err
+		}
+		entries = all
+	} else {
+		fields := make([]string, len(uids))
+		for i, uid := range uids {
+			fields[i] = handField(uid)
+		}
+		values, err := r.client.HMGet(ctx, key, fields...).Result()
+		if err != nil {
+			return nil, err
+		}
+		entries = make(map[string]string, len(fields))
+		for i, v := range values {
+			raw, _ := v.(string)
+			entries[fields[i]] = raw
+		}
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	// One HDEL per hand tells which were actually up; a hand raised
+	// meanwhile by someone else stays.
+	pipe := r.client.Pipeline()
+	dels := make(map[string]*redis.IntCmd, len(entries))
+	for field := range entries {
+		dels[field] = pipe.HDel(ctx, key, field)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	var lowered []Hand
+	for field, cmd := range dels {
+		if cmd.Val() != 1 {
+			continue
+		}
+		var h Hand
+		if err := json.Unmarshal([]byte(entries[field]), &h); err != nil {
+			uid, _ := strconv.ParseUint(field, 10, 64)
+			h = Hand{UID: uint(uid)}
+		}
+		lowered = append(lowered, h)
+	}
+	sortHands(lowered)
+	return lowered, nil
+}
+
+func (r *Redis) Hands(ctx context.Context, room string) ([]Hand, error) {
+	entries, err := r.client.HGetAll(ctx, r.handsKey(room)).Result()
+	if err != nil {
+		return nil, err
+	}
+	hands := make([]Hand, 0, len(entries))
+	for _, raw := range entries {
+		var h Hand
+		if err := json.Unmarshal([]byte(raw), &h); err == nil {
+			hands = append(hands, h)
+		}
+	}
+	sortHands(hands)
+	return hands, nil
+}
+
+func handField(uid uint) string { return strconv.FormatUint(uint64(uid), 10) }
+
+// sortHands puts hands in the order they went up.
+func sortHands(hands []Hand) {
+	sort.Slice(hands, func(i, j int) bool {
+		if hands[i].RaisedAt != hands[j].RaisedAt {
+			return hands[i].RaisedAt < hands[j].RaisedAt
+		}
+		return hands[i].UID < hands[j].UID
+	})
+}
+
+func (r *Redis) Lease(ctx context.Context, name string, ttl time.Duration) (bool, error) {
+	ok, err := leaseScript.Run(ctx, r.client, []string{r.leaseKey(name)}, r.node, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return ok == 1, nil
+}
+
+// liveMembers decodes a room hash, dropping (and deleting) entries of nodes
+// that no longer heartbeat.
+func (r *Redis) liveMembers(ctx context.Context, room string, entries map[string]string) ([]Member, error) {
+	members := make(map[string]Member, len(entries))
+	alive := make(map[string]bool)
+	for identity, raw := range entries {
+		var m Member
+		if err := json.Unmarshal([]byte(raw), &m); err != nil {
+			continue
+		}
+		members[identity] = m
+		alive[m.Node] = false
+	}
+	if len(alive) == 0 {
+		return nil, nil
+	}
+
+	pipe := r.client.Pipeline()
+	checks := make(map[string]*redis.IntCmd, len(alive))
+	for node := range alive {
+		checks[node] = pipe.Exists(ctx, r.nodeKey(node))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	for node, cmd := range checks {
+		alive[node] = cmd.Val() == 1
+	}
+	for identity, m := range members {
+		if !alive[m.Node] {
+			delete(members, identity)
+			_ = r.release(ctx, room, identity, m.Node)
+		}
+	}
+	return sortedMembers(members), nil
+}
+
+// Close stops the heartbeat and the subscription and marks the node dead, so
+// its presence entries stop counting right away.
+func (r *Redis) Close() error {
+	r.stop()
+	<-r.done
+	r.mu.Lock()
+	ps := r.pubsub
+	r.pubsub = nil
+	r.mu.Unlock()
+	if ps != nil {
+		_ = ps.Close()
+	}
+	return r.client.Del(context.Background(), r.nodeKey(r.node)).Err()
+}
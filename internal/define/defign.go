@@ -1,3 +1,66 @@
 package define
 
-var MyKey = "meeting"
+import "time"
+
+var (
+	// AccessTokenTTL is the default lifetime of a JWT access token
+	// (override with ACCESS_TOKEN_TTL).
+	AccessTokenTTL = 15 * time.Minute
+	// RefreshTokenTTL is the default lifetime of a refresh token
+	// (override with REFRESH_TOKEN_TTL).
+	RefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// GuestTokenMaxTTL caps guest tokens, which otherwise live until the meeting
+// ends. Guests cannot refresh, so the token is their whole session.
+var GuestTokenMaxTTL = 12 * time.Hour
+
+// DefaultPublicBaseURL is used in links handed out of band (calendar feeds,
+// join links) when PUBLIC_BASE_URL is not set.
+const DefaultPublicBaseURL = "http://localhost:8080"
+
+// CalendarCancelledRetention is how long deleted rooms stay in calendar
+// feeds as cancelled events, so subscribed clients remove them.
+var CalendarCancelledRetention = 30 * 24 * time.Hour
+
+var (
+	// WebhookMaxAttempts is how many times a delivery is tried before it is
+	// marked failed.
+	WebhookMaxAttempts = 10
+	// WebhookTimeout bounds a single delivery request.
+	WebhookTimeout = 10 * time.Second
+	// WebhookPollInterval is how often the worker looks for due retries.
+	WebhookPollInterval = 5 * time.Second
+)
+
+var (
+	// MeetingEndWarning is how long before the scheduled end peers get a
+	// meeting_ending event (override with MEETING_END_WARNING).
+	MeetingEndWarning = 5 * time.Minute
+	// MeetingSchedulerInterval is how often running meetings are checked
+	// against their end time.
+	MeetingSchedulerInterval = 15 * time.Second
+	// MaxMeetingExtension caps a single extend request.
+	MaxMeetingExtension = 4 * time.Hour
+)
+
+const (
+	// DefaultRedisURL is used by the redis signaling broker when REDIS_URL
+	// is not set.
+	DefaultRedisURL = "redis://127.0.0.1:6379/0"
+	// SignalBrokerPrefix namespaces the broker's keys and channel in Redis.
+	SignalBrokerPrefix = "gomeetings:signal:"
+)
+
+// SignalBrokerTimeout bounds a single broker call (publish, presence update).
+var SignalBrokerTimeout = 3 * time.Second
+
+var (
+	// SignalResumeGrace is how long a dropped signaling connection keeps its
+	// slot and buffers messages for a resume (override with
+	// SIGNAL_RESUME_GRACE).
+	SignalResumeGrace = 30 * time.Second
+	// SignalResumeBuffer caps the messages held for a dropped connection;
+	// past it the session ends instead of resuming with gaps.
+	SignalResumeBuffer = 128
+)
@@ -1,13 +1,15 @@
 package helper
 
 import (
-	"GoMeetings/internal/define"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 
 	"crypto/md5"
 	"fmt"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	uuid "github.com/satori/go.uuid"
@@ -17,6 +19,9 @@ type UserClaims struct {
 	Id      uint   `json:"id"`
 	Name    string `json:"name"`
 	IsAdmin int    `json:"is_admin"`
+	Family  string `json:"fid,omitempty"` // refresh-token family, revoked on logout
+	Guest   bool   `json:"guest,omitempty"`
+	Room    string `json:"room,omitempty"` // room identity a guest token is scoped to
 	jwt.RegisteredClaims
 }
 
@@ -27,14 +32,23 @@ func GenerateUUID() string {
 	return uuid.NewV4().String()
 }
 
-func GenerateToken(id uint, name string) (string, error) {
-	userClaim := &UserClaims{
-		Id:   id,
-		Name: name,
+// GenerateToken signs claims with the active key as an access token valid
+// for ttl. The iss, iat, exp and jti registered claims are filled in and
+// reflected back into claims, and the key id is sent in the kid header.
+func GenerateToken(claims *UserClaims, ttl time.Duration) (string, error) {
+	keys, err := currentKeyRing()
+	if err != nil {
+		return "", err
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, userClaim)
+	now := time.Now()
+	claims.Issuer = keys.issuer
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.ExpiresAt = jwt.NewNumericDate(now.Add(ttl))
+	claims.ID = GenerateUUID()
+	token := jwt.NewWithClaims(keys.active.method, claims)
+	token.Header["kid"] = keys.active.kid
 
-	tokenString, err := token.SignedString([]byte(define.MyKey))
+	tokenString, err := token.SignedString(keys.active.sign)
 	if err != nil {
 		return "", err
 	}
@@ -42,10 +56,22 @@ func GenerateToken(id uint, name string) (string, error) {
 }
 
 func AnalyzeToken(tokenString string) (*UserClaims, error) {
+	keys, err := currentKeyRing()
+	if err != nil {
+		return nil, err
+	}
 	userClaim := &UserClaims{}
 	claims, err := jwt.ParseWithClaims(tokenString, userClaim, func(token *jwt.Token) (interface{}, error) {
-		return []byte(define.MyKey), nil
-	})
+		kid, _ := token.Header["kid"].(string)
+		key, ok := keys.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+		return key.verify, nil
+	}, jwt.WithExpirationRequired(), jwt.WithIssuedAt(), jwt.WithIssuer(keys.issuer))
 	if err != nil {
 		return nil, err
 	}
@@ -55,6 +81,20 @@ func AnalyzeToken(tokenString string) (*UserClaims, error) {
 	return userClaim, nil
 }
 
+// DurationFromEnv parses the named environment variable as a time.Duration,
+// returning def when it is unset or invalid.
+func DurationFromEnv(key string, def time.Duration) time.Duration {
+	raw := strings.TrimSpace(os.Getenv(key))
+	if raw == "" {
+		return def
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d <= 0 {
+		return def
+	}
+	return d
+}
+
 func Encode(obj interface{}) string {
 	b, err := json.Marshal(obj)
 	if err != nil {
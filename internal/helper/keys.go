@@ -0,0 +1,293 @@
+package helper
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signingKey is one entry of the key ring. sign is nil for verify-only keys,
+// which lets a retired key keep validating tokens until they expire.
+type signingKey struct {
+	kid    string
+	method jwt.SigningMethod
+	sign   interface{}
+	verify interface{}
+}
+
+type keyRing struct {
+	active *signingKey
+	keys   map[string]*signingKey
+	issuer string
+}
+
+// KeyConfig describes one signing key in the JWT_KEYS_FILE document.
+type KeyConfig struct {
+	Kid            string `json:"kid"`
+	Alg            string `json:"alg"`
+	Secret         string `json:"secret,omitempty"`
+	SecretFile     string `json:"secret_file,omitempty"`
+	PrivateKeyFile string `json:"private_key_file,omitempty"`
+	PublicKeyFile  string `json:"public_key_file,omitempty"`
+}
+
+// KeysConfig is the JWT_KEYS_FILE document. Active names the kid used to sign
+// new tokens; every listed key is accepted for verification.
+type KeysConfig struct {
+	Active string      `json:"active"`
+	Issuer string      `json:"issuer,omitempty"`
+	Keys   []KeyConfig `json:"keys"`
+}
+
+// JSONWebKey is the public part of an asymmetric signing key (RFC 7517).
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+const defaultIssuer = "GoMeetings"
+
+var (
+	ringMu sync.RWMutex
+	ring   *keyRing
+)
+
+// LoadSigningKeys (re)loads the key ring from the environment:
+//
+//   - JWT_KEYS_FILE: JSON KeysConfig with one or more HS256/RS256/EdDSA keys
+//   - JWT_SECRET (+ JWT_KID): a single HS256 key
+//
+// JWT_ACTIVE_KID overrides the active key and JWT_ISSUER the iss claim. With
+// nothing configured an ephemeral random key is generated, so tokens do not
+// survive a restart.
+func LoadSigningKeys() error {
+	cfg, err := keysConfigFromEnv()
+	if err != nil {
+		return err
+	}
+	r, err := buildKeyRing(cfg)
+	if err != nil {
+		return err
+	}
+	ringMu.Lock()
+	ring = r
+	ringMu.Unlock()
+	return nil
+}
+
+func currentKeyRing() (*keyRing, error) {
+	ringMu.RLock()
+	r := ring
+	ringMu.RUnlock()
+	if r != nil {
+		return r, nil
+	}
+	if err := LoadSigningKeys(); err != nil {
+		return nil, err
+	}
+	ringMu.RLock()
+	defer ringMu.RUnlock()
+	return ring, nil
+}
+
+func keysConfigFromEnv() (*KeysConfig, error) {
+	cfg := &KeysConfig{}
+	if path := strings.TrimSpace(os.Getenv("JWT_KEYS_FILE")); path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read JWT_KEYS_FILE: %w", err)
+		}
+		if err := json.Unmarshal(raw, cfg); err != nil {
+			return nil, fmt.Errorf("parse JWT_KEYS_FILE: %w", err)
+		}
+	} else if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		kid := strings.TrimSpace(os.Getenv("JWT_KID"))
+		if kid == "" {
+			kid = "default"
+		}
+		cfg.Active = kid
+		cfg.Keys = []KeyConfig{{Kid: kid, Alg: jwt.SigningMethodHS256.Alg(), Secret: secret}}
+	} else {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		log.Println("helper: no JWT_KEYS_FILE or JWT_SECRET configured, using an ephemeral signing key")
+		cfg.Active = "ephemeral"
+		cfg.Keys = []KeyConfig{{Kid: "ephemeral", Alg: jwt.SigningMethodHS256.Alg(), Secret: string(buf)}}
+	}
+	if kid := strings.TrimSpace(os.Getenv("JWT_ACTIVE_KID")); kid != "" {
+		cfg.Active = kid
+	}
+	if issuer := strings.TrimSpace(os.Getenv("JWT_ISSUER")); issuer != "" {
+		cfg.Issuer = issuer
+	}
+	return cfg, nil
+}
+
+func buildKeyRing(cfg *KeysConfig) (*keyRing, error) {
+	r := &keyRing{
+		keys:   make(map[string]*signingKey, len(cfg.Keys)),
+		issuer: cfg.Issuer,
+	}
+	if r.issuer == "" {
+		r.issuer = defaultIssuer
+	}
+	for _, kc := range cfg.Keys {
+		key, err := loadSigningKey(kc)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %q: %w", kc.Kid, err)
+		}
+		if _, dup := r.keys[key.kid]; dup {
+			return nil, fmt.Errorf("jwt key %q is defined twice", key.kid)
+		}
+		r.keys[key.kid] = key
+	}
+	active, ok := r.keys[cfg.Active]
+	if !ok {
+		return nil, fmt.Errorf("active jwt key %q is not configured", cfg.Active)
+	}
+	if active.sign == nil {
+		return nil, fmt.Errorf("active jwt key %q has no private key", cfg.Active)
+	}
+	r.active = active
+	return r, nil
+}
+
+func loadSigningKey(kc KeyConfig) (*signingKey, error) {
+	kid := strings.TrimSpace(kc.Kid)
+	if kid == "" {
+		return nil, errors.New("kid is required")
+	}
+	key := &signingKey{kid: kid}
+	switch strings.ToUpper(kc.Alg) {
+	case "HS256":
+		secret := kc.Secret
+		if kc.SecretFile != "" {
+			raw, err := os.ReadFile(kc.SecretFile)
+			if err != nil {
+				return nil, err
+			}
+			secret = strings.TrimSpace(string(raw))
+		}
+		if secret == "" {
+			return nil, errors.New("secret is required for HS256")
+		}
+		key.method = jwt.SigningMethodHS256
+		key.sign = []byte(secret)
+		key.verify = []byte(secret)
+	case "RS256":
+		key.method = jwt.SigningMethodRS256
+		if kc.PrivateKeyFile != "" {
+			raw, err := os.ReadFile(kc.PrivateKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			priv, err := jwt.ParseRSAPrivateKeyFromPEM(raw)
+			if err != nil {
+				return nil, err
+			}
+			key.sign = priv
+			key.verify = &priv.PublicKey
+		} else if kc.PublicKeyFile != "" {
+			raw, err := os.ReadFile(kc.PublicKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			pub, err := jwt.ParseRSAPublicKeyFromPEM(raw)
+			if err != nil {
+				return nil, err
+			}
+			key.verify = pub
+		} else {
+			return nil, errors.New("private_key_file or public_key_file is required for RS256")
+		}
+	case "EDDSA":
+		key.method = jwt.SigningMethodEdDSA
+		if kc.PrivateKeyFile != "" {
+			raw, err := os.ReadFile(kc.PrivateKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			priv, err := jwt.ParseEdPrivateKeyFromPEM(raw)
+			if err != nil {
+				return nil, err
+			}
+			edPriv, ok := priv.(ed25519.PrivateKey)
+			if !ok {
+				return nil, errors.New("private key is not Ed25519")
+			}
+			key.sign = edPriv
+			key.verify = edPriv.Public()
+		} else if kc.PublicKeyFile != "" {
+			raw, err := os.ReadFile(kc.PublicKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			pub, err := jwt.ParseEdPublicKeyFromPEM(raw)
+			if err != nil {
+				return nil, err
+			}
+			key.verify = pub
+		} else {
+			return nil, errors.New("private_key_file or public_key_file is required for EdDSA")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported alg %q", kc.Alg)
+	}
+	return key, nil
+}
+
+// JWKS returns the public keys of every asymmetric key in the ring so other
+// services can verify tokens. HMAC keys are never published.
+func JWKS() (JSONWebKeySet, error) {
+	set := JSONWebKeySet{Keys: []JSONWebKey{}}
+	r, err := currentKeyRing()
+	if err != nil {
+		return set, err
+	}
+	for _, key := range r.keys {
+		switch pub := key.verify.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JSONWebKey{
+				Kty: "RSA",
+				Kid: key.kid,
+				Use: "sig",
+				Alg: key.method.Alg(),
+				N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JSONWebKey{
+				Kty: "OKP",
+				Kid: key.kid,
+				Use: "sig",
+				Alg: key.method.Alg(),
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+	return set, nil
+}
@@ -0,0 +1,67 @@
+package helper
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestKeyRotation(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "ed25519.pem")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	setRing := func(active string) {
+		r, err := buildKeyRing(&KeysConfig{
+			Active: active,
+			Keys: []KeyConfig{
+				{Kid: "old", Alg: "HS256", Secret: "old-secret"},
+				{Kid: "new", Alg: "EdDSA", PrivateKeyFile: keyFile},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ringMu.Lock()
+		ring = r
+		ringMu.Unlock()
+	}
+
+	setRing("old")
+	oldToken, err := GenerateToken(&UserClaims{Id: 1, Name: "alice"}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	setRing("new")
+	newToken, err := GenerateToken(&UserClaims{Id: 2, Name: "bob"}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tok := range []string{oldToken, newToken} {
+		if _, err := AnalyzeToken(tok); err != nil {
+			t.Fatalf("token rejected after rotation: %v", err)
+		}
+	}
+
+	set, err := JWKS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(set.Keys) != 1 || set.Keys[0].Kid != "new" || set.Keys[0].Crv != "Ed25519" {
+		t.Fatalf("unexpected jwks: %+v", set.Keys)
+	}
+}
@@ -0,0 +1,67 @@
+package helper
+
+import (
+	"crypto/subtle"
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordCost is the bcrypt work factor used for new hashes. Stored hashes
+// with a lower cost are upgraded on the next successful login.
+const PasswordCost = 12
+
+// MaxPasswordLength is the longest password bcrypt accepts, in bytes.
+const MaxPasswordLength = 72
+
+var ErrPasswordTooLong = errors.New("password must be at most 72 bytes")
+
+// HashPassword returns a bcrypt hash of the password. bcrypt embeds a random
+// per-hash salt and its cost in the encoded string.
+func HashPassword(password string) (string, error) {
+	if password == "" {
+		return "", errors.New("password is empty")
+	}
+	if len(password) > MaxPasswordLength {
+		return "", ErrPasswordTooLong
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), PasswordCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword reports whether password matches the stored hash. needsRehash
+// is true when the hash is a legacy MD5 digest or uses an outdated bcrypt
+// cost, so the caller should store a fresh HashPassword result.
+func CheckPassword(stored, password string) (ok bool, needsRehash bool) {
+	if stored == "" || password == "" {
+		return false, false
+	}
+	if isLegacyMd5(stored) {
+		digest := GetMd5(password)
+		if subtle.ConstantTimeCompare([]byte(strings.ToLower(stored)), []byte(digest)) != 1 {
+			return false, false
+		}
+		return true, true
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(stored), []byte(password)); err != nil {
+		return false, false
+	}
+	cost, err := bcrypt.Cost([]byte(stored))
+	return true, err != nil || cost < PasswordCost
+}
+
+func isLegacyMd5(stored string) bool {
+	if len(stored) != 32 {
+		return false
+	}
+	for _, r := range stored {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return false
+		}
+	}
+	return true
+}
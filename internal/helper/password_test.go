@@ -0,0 +1,37 @@
+package helper
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckPassword(t *testing.T) {
+	hash, err := HashPassword("123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, rehash := CheckPassword(hash, "123456"); !ok || rehash {
+		t.Fatalf("bcrypt hash: ok=%v rehash=%v", ok, rehash)
+	}
+	if ok, _ := CheckPassword(hash, "654321"); ok {
+		t.Fatal("wrong password accepted")
+	}
+
+	legacy := GetMd5("123456")
+	if ok, rehash := CheckPassword(legacy, "123456"); !ok || !rehash {
+		t.Fatalf("legacy md5: ok=%v rehash=%v", ok, rehash)
+	}
+	if ok, _ := CheckPassword(legacy, "654321"); ok {
+		t.Fatal("wrong password accepted for legacy hash")
+	}
+}
+
+func TestHashPasswordRejectsLongPasswords(t *testing.T) {
+	if _, err := HashPassword(strings.Repeat("a", MaxPasswordLength)); err != nil {
+		t.Fatalf("72 bytes: %v", err)
+	}
+	if _, err := HashPassword(strings.Repeat("a", MaxPasswordLength+1)); !errors.Is(err, ErrPasswordTooLong) {
+		t.Fatalf("73 bytes: err = %v", err)
+	}
+}
@@ -0,0 +1,177 @@
+// Package ical writes the iCalendar (RFC 5545) subset needed to publish
+// meetings: a VCALENDAR of VEVENTs with recurrence, exceptions and
+// cancellations.
+package ical
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	StatusConfirmed = "CONFIRMED"
+	StatusCancelled = "CANCELLED"
+)
+
+const maxLineOctets = 75
+
+type Calendar struct {
+	ProdID string
+	Name   string
+	// Method is "PUBLISH" for downloads and feeds.
+	Method string
+	Events []Event
+}
+
+type Organizer struct {
+	Name  string
+	Email string
+}
+
+type Event struct {
+	UID      string
+	Sequence int
+	Stamp    time.Time
+	Start    time.Time
+	End      time.Time
+	Summary  string
+	// Description, Location and URL are free text.
+	Description string
+	Location    string
+	URL         string
+	Organizer   *Organizer
+	Status      string
+	RRule       string
+	ExDates     []time.Time
+	// RecurrenceID marks the event as an override of one occurrence of the
+	// series with the same UID.
+	RecurrenceID time.Time
+}
+
+// Marshal renders the calendar with CRLF line endings and folded lines.
+func (c *Calendar) Marshal() []byte {
+	var buf bytes.Buffer
+	_ = c.Encode(&buf)
+	return buf.Bytes()
+}
+
+func (c *Calendar) Encode(w io.Writer) error {
+	e := &encoder{w: w}
+	e.line("BEGIN", "VCALENDAR")
+	e.line("VERSION", "2.0")
+	e.line("PRODID", c.ProdID)
+	e.line("CALSCALE", "GREGORIAN")
+	if c.Method != "" {
+		e.line("METHOD", c.Method)
+	}
+	if c.Name != "" {
+		e.line("X-WR-CALNAME", escapeText(c.Name))
+	}
+	for i := range c.Events {
+		c.Events[i].encode(e)
+	}
+	e.line("END", "VCALENDAR")
+	return e.err
+}
+
+func (ev *Event) encode(e *encoder) {
+	e.line("BEGIN", "VEVENT")
+	e.line("UID", ev.UID)
+	e.line("SEQUENCE", strconv.Itoa(ev.Sequence))
+	e.line("DTSTAMP", formatTime(ev.Stamp))
+	if !ev.RecurrenceID.IsZero() {
+		e.line("RECURRENCE-ID", formatTime(ev.RecurrenceID))
+	}
+	e.line("DTSTART", formatTime(ev.Start))
+	e.line("DTEND", formatTime(ev.End))
+	if ev.RRule != "" {
+		e.line("RRULE", ev.RRule)
+	}
+	if len(ev.ExDates) > 0 {
+		dates := make([]string, 0, len(ev.ExDates))
+		for _, t := range ev.ExDates {
+			dates = append(dates, formatTime(t))
+		}
+		e.line("EXDATE", strings.Join(dates, ","))
+	}
+	e.line("SUMMARY", escapeText(ev.Summary))
+	if ev.Description != "" {
+		e.line("DESCRIPTION", escapeText(ev.Description))
+	}
+	if ev.Location != "" {
+		e.line("LOCATION", escapeText(ev.Location))
+	}
+	if ev.URL != "" {
+		e.line("URL", ev.URL)
+	}
+	if ev.Organizer != nil {
+		name := "ORGANIZER"
+		if ev.Organizer.Name != "" {
+			name += ";CN=" + quoteParam(ev.Organizer.Name)
+		}
+		value := "mailto:" + ev.Organizer.Email
+		if ev.Organizer.Email == "" {
+			value = "mailto:noreply@invalid"
+		}
+		e.line(name, value)
+	}
+	if ev.Status != "" {
+		e.line("STATUS", ev.Status)
+	}
+	e.line("END", "VEVENT")
+}
+
+type encoder struct {
+	w   io.Writer
+	err error
+}
+
+// line writes "name:value", folded at 75 octets without splitting UTF-8
+// sequences.
+func (e *encoder) line(name, value string) {
+	if e.err != nil {
+		return
+	}
+	s := name + ":" + value
+	var out strings.Builder
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(s[cut]) {
+			cut--
+		}
+		out.WriteString(s[:cut])
+		out.WriteString("\r\n ")
+		s = s[cut:]
+		// Continuation lines start with a space, which counts.
+		limit = maxLineOctets - 1
+	}
+	out.WriteString(s)
+	out.WriteString("\r\n")
+	_, e.err = io.WriteString(e.w, out.String())
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+func quoteParam(s string) string {
+	s = strings.NewReplacer(`"`, "'", "\r", "", "\n", " ").Replace(s)
+	if strings.ContainsAny(s, ":;,") {
+		return `"` + s + `"`
+	}
+	return s
+}
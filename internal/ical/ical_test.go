@@ -0,0 +1,64 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMarshalEvent(t *testing.T) {
+	start := time.Date(2026, 10, 7, 9, 30, 0, 0, time.UTC)
+	cal := Calendar{
+		ProdID: "-//GoMeetings//EN",
+		Method: "PUBLISH",
+		Events: []Event{{
+			UID:         "room-1@gomeetings",
+			Sequence:    2,
+			Stamp:       start,
+			Start:       start,
+			End:         start.Add(time.Hour),
+			Summary:     "Standup; team, daily",
+			Description: "line one\nline two",
+			Organizer:   &Organizer{Name: "Doe, Jane"},
+			Status:      StatusConfirmed,
+			RRule:       "FREQ=WEEKLY;BYDAY=WE",
+			ExDates:     []time.Time{start.AddDate(0, 0, 7)},
+		}},
+	}
+	out := string(cal.Marshal())
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"SEQUENCE:2\r\n",
+		"DTSTART:20261007T093000Z\r\n",
+		"RRULE:FREQ=WEEKLY;BYDAY=WE\r\n",
+		"EXDATE:20261014T093000Z\r\n",
+		`SUMMARY:Standup\; team\, daily` + "\r\n",
+		`DESCRIPTION:line one\nline two` + "\r\n",
+		`ORGANIZER;CN="Doe, Jane":mailto:noreply@invalid` + "\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+}
+
+func TestLineFolding(t *testing.T) {
+	cal := Calendar{ProdID: "x", Events: []Event{{
+		UID:     "u",
+		Summary: strings.Repeat("é", 100),
+	}}}
+	for _, line := range strings.Split(string(cal.Marshal()), "\r\n") {
+		if len(line) > maxLineOctets {
+			t.Fatalf("line longer than %d octets: %q", maxLineOctets, line)
+		}
+		if strings.HasPrefix(line, " \xa9") {
+			t.Fatalf("fold split a UTF-8 sequence: %q", line)
+		}
+	}
+	unfolded := strings.ReplaceAll(string(cal.Marshal()), "\r\n ", "")
+	if !strings.Contains(unfolded, "SUMMARY:"+strings.Repeat("é", 100)+"\r\n") {
+		t.Fatal("unfolded summary does not round-trip")
+	}
+}
@@ -2,12 +2,24 @@ package middlewares
 
 import (
 	"GoMeetings/internal/helper"
+	"GoMeetings/internal/models"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
+// Auth accepts registered-user access tokens only.
 func Auth() gin.HandlerFunc {
+	return authenticate(false)
+}
+
+// AuthAllowGuest also accepts room-scoped guest tokens; handlers must check
+// that the requested room matches the token's scope.
+func AuthAllowGuest() gin.HandlerFunc {
+	return authenticate(true)
+}
+
+func authenticate(allowGuest bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		auth := c.GetHeader("Authorization")
 		userClaims, err := helper.AnalyzeToken(auth)
@@ -24,7 +36,25 @@ func Auth() gin.HandlerFunc {
 			c.Abort()
 			c.JSON(http.StatusOK, gin.H{
 				"code": http.StatusUnauthorized,
-				"msg":  "Unauthorized Admin",
+				"msg":  "Unauthorized",
+			})
+			return
+		}
+
+		if userClaims.Guest && !allowGuest {
+			c.Abort()
+			c.JSON(http.StatusOK, gin.H{
+				"code": http.StatusForbidden,
+				"msg":  "Guest Not Allowed",
+			})
+			return
+		}
+
+		if revoked, err := models.TokenFamilyRevoked(userClaims.Family); err != nil || revoked {
+			c.Abort()
+			c.JSON(http.StatusOK, gin.H{
+				"code": http.StatusUnauthorized,
+				"msg":  "Token Revoked",
 			})
 			return
 		}
@@ -33,3 +63,31 @@ func Auth() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// RequireAdmin must run after Auth. The role is re-read from the database so a
+// demoted or disabled admin loses access before their token expires.
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userClaims, ok := c.MustGet("user_claims").(*helper.UserClaims)
+		if !ok || userClaims.IsAdmin != 1 {
+			c.Abort()
+			c.JSON(http.StatusOK, gin.H{
+				"code": http.StatusForbidden,
+				"msg":  "Forbidden",
+			})
+			return
+		}
+
+		var user models.UserBasic
+		if err := models.DB.First(&user, userClaims.Id).Error; err != nil || !user.IsAdmin() || user.Disabled {
+			c.Abort()
+			c.JSON(http.StatusOK, gin.H{
+				"code": http.StatusForbidden,
+				"msg":  "Forbidden",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
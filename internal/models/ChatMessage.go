@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ChatMessage is a meeting chat line. RecipientUid is 0 for messages to the
+// whole room; otherwise only the sender and recipient can see it. Deleted
+// messages are soft-deleted.
+type ChatMessage struct {
+	gorm.Model
+	Rid               uint       `gorm:"column:rid;type:int(11);not null;index" json:"rid"`
+	SenderUid         uint       `gorm:"column:sender_uid;type:int(11);not null" json:"sender_uid"`
+	SenderIdentity    string     `gorm:"column:sender_identity;type:varchar(100);not null" json:"sender_identity"`
+	SenderName        string     `gorm:"column:sender_name;type:varchar(64);not null" json:"sender_name"`
+	RecipientUid      uint       `gorm:"column:recipient_uid;type:int(11);not null;default:0" json:"recipient_uid"`
+	RecipientIdentity string     `gorm:"column:recipient_identity;type:varchar(100)" json:"recipient_identity"`
+	Content           string     `gorm:"column:content;type:text;not null" json:"content"`
+	EditedAt          *time.Time `gorm:"column:edited_at;type:datetime" json:"edited_at"`
+}
+
+func (table *ChatMessage) TableName() string {
+	return "chat_message"
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ParticipantSession is one signaling connection of a user to a room, from
+// the websocket joining to it leaving. LeftAt is nil while connected.
+type ParticipantSession struct {
+	gorm.Model
+	Rid          uint       `gorm:"column:rid;type:int(11);not null;index:idx_session_rid_uid" json:"rid"`
+	Uid          uint       `gorm:"column:uid;type:int(11);not null;index:idx_session_rid_uid" json:"uid"`
+	UserIdentity string     `gorm:"column:user_identity;type:varchar(100);not null" json:"user_identity"`
+	DisplayName  string     `gorm:"column:display_name;type:varchar(64);not null" json:"display_name"`
+	IsGuest      bool       `gorm:"column:is_guest;type:tinyint(1);not null;default:0" json:"is_guest"`
+	JoinedAt     time.Time  `gorm:"column:joined_at;type:datetime(3);not null" json:"joined_at"`
+	LeftAt       *time.Time `gorm:"column:left_at;type:datetime(3)" json:"left_at"`
+}
+
+func (table *ParticipantSession) TableName() string {
+	return "participant_session"
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Poll is a question put to the room by a host. Anonymous polls never expose
+// who voted for what, not even to hosts.
+type Poll struct {
+	gorm.Model
+	Rid       uint       `gorm:"column:rid;type:int(11);not null;index" json:"rid"`
+	CreatedBy uint       `gorm:"column:created_by;type:int(11);not null" json:"created_by"`
+	Question  string     `gorm:"column:question;type:varchar(500);not null" json:"question"`
+	Multiple  bool       `gorm:"column:multiple;type:tinyint(1);not null;default:0" json:"multiple"`   //voters may pick several options
+	Anonymous bool       `gorm:"column:anonymous;type:tinyint(1);not null;default:0" json:"anonymous"` //results carry counts only
+	ClosedAt  *time.Time `gorm:"column:closed_at;type:datetime" json:"closed_at"`
+}
+
+func (table *Poll) TableName() string {
+	return "poll"
+}
@@ -0,0 +1,14 @@
+package models
+
+import "gorm.io/gorm"
+
+type PollOption struct {
+	gorm.Model
+	PollID   uint   `gorm:"column:poll_id;type:int(11);not null;index" json:"poll_id"`
+	Position int    `gorm:"column:position;type:int(11);not null" json:"position"`
+	Text     string `gorm:"column:text;type:varchar(200);not null" json:"text"`
+}
+
+func (table *PollOption) TableName() string {
+	return "poll_option"
+}
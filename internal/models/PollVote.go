@@ -0,0 +1,16 @@
+package models
+
+import "gorm.io/gorm"
+
+// PollVote is one selected option. Changing a vote replaces the voter's rows.
+type PollVote struct {
+	gorm.Model
+	PollID    uint   `gorm:"column:poll_id;type:int(11);not null;uniqueIndex:idx_vote_poll_option_uid" json:"poll_id"`
+	OptionID  uint   `gorm:"column:option_id;type:int(11);not null;uniqueIndex:idx_vote_poll_option_uid" json:"option_id"`
+	Uid       uint   `gorm:"column:uid;type:int(11);not null;uniqueIndex:idx_vote_poll_option_uid" json:"uid"`
+	VoterName string `gorm:"column:voter_name;type:varchar(64);not null" json:"voter_name"`
+}
+
+func (table *PollVote) TableName() string {
+	return "poll_vote"
+}
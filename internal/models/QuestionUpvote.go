@@ -0,0 +1,13 @@
+package models
+
+import "gorm.io/gorm"
+
+type QuestionUpvote struct {
+	gorm.Model
+	QuestionID uint `gorm:"column:question_id;type:int(11);not null;uniqueIndex:idx_upvote_question_uid" json:"question_id"`
+	Uid        uint `gorm:"column:uid;type:int(11);not null;uniqueIndex:idx_upvote_question_uid" json:"uid"`
+}
+
+func (table *QuestionUpvote) TableName() string {
+	return "question_upvote"
+}
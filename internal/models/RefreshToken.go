@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RefreshToken stores the SHA-256 of an issued refresh token. Tokens issued
+// from one login share a FamilyID; rotation marks the old row used, and
+// presenting a used token revokes the whole family.
+type RefreshToken struct {
+	gorm.Model
+	Uid       uint       `gorm:"column:uid;type:int(11);not null;index" json:"uid"`
+	FamilyID  string     `gorm:"column:family_id;type:varchar(36);not null;index" json:"family_id"`
+	TokenHash string     `gorm:"column:token_hash;type:varchar(64);not null;uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `gorm:"column:expires_at;type:datetime;not null" json:"expires_at"`
+	UsedAt    *time.Time `gorm:"column:used_at;type:datetime" json:"used_at"`
+	RevokedAt *time.Time `gorm:"column:revoked_at;type:datetime" json:"revoked_at"`
+}
+
+func (table *RefreshToken) TableName() string {
+	return "refresh_token"
+}
+
+// TokenFamilyRevoked reports whether any token of the family has been revoked.
+func TokenFamilyRevoked(familyID string) (bool, error) {
+	var count int64
+	err := DB.Model(&RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NOT NULL", familyID).
+		Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,17 @@
+package models
+
+import "gorm.io/gorm"
+
+// RoomBan keeps a user out of a room after a host removed them. Bans are per
+// account, so they only hold against registered users.
+type RoomBan struct {
+	gorm.Model
+	Rid      uint   `gorm:"column:rid;type:int(11);not null;uniqueIndex:idx_ban_rid_uid" json:"rid"`
+	Uid      uint   `gorm:"column:uid;type:int(11);not null;uniqueIndex:idx_ban_rid_uid" json:"uid"`
+	BannedBy uint   `gorm:"column:banned_by;type:int(11);not null" json:"banned_by"`
+	Reason   string `gorm:"column:reason;type:varchar(255)" json:"reason"`
+}
+
+func (table *RoomBan) TableName() string {
+	return "room_ban"
+}
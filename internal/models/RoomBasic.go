@@ -8,15 +8,33 @@ import (
 
 type RoomBasic struct {
 	gorm.Model
-	Identify  string    `gorm:"column:identify;type:varchar(36);uniqueIndex;not null" json:"identify"`
-	Name      string    `gorm:"column:name;type:varchar(100);not null" json:"name"`
-	BeginAt   time.Time `gorm:"column:begin_at;type:datetime;not null" json:"begin_at"`
-	EndAt     time.Time `gorm:"column:end_at;type:datetime;not null" json:"end_at"`
-	CreateID  uint      `gorm:"column:create_id;type:int(20);not null" json:"create_id"` //create_id
-	JoinCode  string    `gorm:"column:join_code;type:varchar(16);not null" json:"-"`
-	ShortCode string    `gorm:"column:short_code;type:varchar(16)" json:"-"`
+	Identify     string     `gorm:"column:identify;type:varchar(36);uniqueIndex;not null" json:"identify"`
+	Name         string     `gorm:"column:name;type:varchar(100);not null" json:"name"`
+	BeginAt      time.Time  `gorm:"column:begin_at;type:datetime;not null" json:"begin_at"`
+	EndAt        time.Time  `gorm:"column:end_at;type:datetime;not null" json:"end_at"`
+	CreateID     uint       `gorm:"column:create_id;type:int(20);not null" json:"create_id"` //create_id
+	JoinCode     string     `gorm:"column:join_code;type:varchar(16);not null" json:"-"`
+	ShortCode    string     `gorm:"column:short_code;type:varchar(16);index" json:"-"`
+	Lobby        bool       `gorm:"column:lobby_enabled;type:tinyint(1);not null;default:0" json:"lobby_enabled"` //hold joiners until admitted
+	Locked       bool       `gorm:"column:locked;type:tinyint(1);not null;default:0" json:"locked"`               //no new joins
+	RRule        string     `gorm:"column:rrule;type:varchar(255);not null;default:''" json:"rrule"`              //RFC 5545 recurrence, BeginAt/EndAt is the first occurrence
+	Sequence     int        `gorm:"column:sequence;type:int(11);not null;default:0" json:"sequence"`              //iCalendar SEQUENCE, bumped on every schedule change
+	ParentID     uint       `gorm:"column:parent_id;type:int(11);not null;default:0;index" json:"parent_id"`      //breakout rooms point at their main room
+	EndedAt      *time.Time `gorm:"column:ended_at;type:datetime" json:"ended_at"`                                //when the last meeting was closed
+	Status       string     `gorm:"column:status;type:varchar(16);not null;default:scheduled;index" json:"status"`
+	CancelReason string     `gorm:"column:cancel_reason;type:varchar(255);not null;default:''" json:"cancel_reason,omitempty"`
 }
 
+// Room lifecycle. A meeting goes scheduled -> live -> ended; a scheduled one
+// can be cancelled instead, or ends unstarted once its time is over. Recurring rooms go back to scheduled after each
+// occurrence until the series is over.
+const (
+	RoomStatusScheduled = "scheduled"
+	RoomStatusLive      = "live"
+	RoomStatusEnded     = "ended"
+	RoomStatusCancelled = "cancelled"
+)
+
 func (table *RoomBasic) TableName() string {
 	return "room_basic"
 }
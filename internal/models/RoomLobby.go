@@ -0,0 +1,24 @@
+package models
+
+import "gorm.io/gorm"
+
+const (
+	LobbyStatusPending  = "pending"
+	LobbyStatusAdmitted = "admitted"
+	LobbyStatusDenied   = "denied"
+)
+
+// RoomLobby is a join request waiting for the host in a room with the lobby
+// enabled. Admitted requests get a RoomUser row.
+type RoomLobby struct {
+	gorm.Model
+	Rid         uint   `gorm:"column:rid;type:int(11);not null;index:idx_lobby_rid_uid" json:"rid"`
+	Uid         uint   `gorm:"column:uid;type:int(11);not null;index:idx_lobby_rid_uid" json:"uid"`
+	DisplayName string `gorm:"column:display_name;type:varchar(64);not null" json:"display_name"`
+	IsGuest     bool   `gorm:"column:is_guest;type:tinyint(1);not null;default:0" json:"is_guest"`
+	Status      string `gorm:"column:status;type:varchar(16);not null;default:pending" json:"status"`
+}
+
+func (table *RoomLobby) TableName() string {
+	return "room_lobby"
+}
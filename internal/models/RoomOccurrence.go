@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RoomOccurrence overrides one occurrence of a recurring room, identified by
+// the start the rule generated for it. Cancelled rows are the exceptions of
+// the series.
+type RoomOccurrence struct {
+	gorm.Model
+	Rid           uint      `gorm:"column:rid;type:int(11);not null;uniqueIndex:idx_occurrence_rid_start" json:"rid"`
+	OriginalStart time.Time `gorm:"column:original_start;type:datetime;not null;uniqueIndex:idx_occurrence_rid_start" json:"original_start"`
+	BeginAt       time.Time `gorm:"column:begin_at;type:datetime;not null" json:"begin_at"`
+	EndAt         time.Time `gorm:"column:end_at;type:datetime;not null" json:"end_at"`
+	Cancelled     bool      `gorm:"column:cancelled;type:tinyint(1);not null;default:0" json:"cancelled"`
+}
+
+func (table *RoomOccurrence) TableName() string {
+	return "room_occurrence"
+}
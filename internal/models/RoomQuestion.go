@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RoomQuestion is an entry of a meeting's Q&A queue. Upvotes mirrors the
+// number of QuestionUpvote rows so the queue can be ordered cheaply.
+type RoomQuestion struct {
+	gorm.Model
+	Rid        uint       `gorm:"column:rid;type:int(11);not null;index" json:"rid"`
+	AskerUid   uint       `gorm:"column:asker_uid;type:int(11);not null" json:"asker_uid"`
+	AskerName  string     `gorm:"column:asker_name;type:varchar(64);not null" json:"asker_name"`
+	Content    string     `gorm:"column:content;type:text;not null" json:"content"`
+	Upvotes    int        `gorm:"column:upvotes;type:int(11);not null;default:0" json:"upvotes"`
+	AnsweredAt *time.Time `gorm:"column:answered_at;type:datetime" json:"answered_at"`
+	AnsweredBy uint       `gorm:"column:answered_by;type:int(11);not null;default:0" json:"answered_by"`
+}
+
+func (table *RoomQuestion) TableName() string {
+	return "room_question"
+}
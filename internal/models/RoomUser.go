@@ -7,8 +7,19 @@ type RoomUser struct {
 	Rid         uint   `gorm:"column:rid;type:int(11);not null" json:"rid"` //room id
 	Uid         uint   `gorm:"column:uid;type:int(11);not null" json:"uid"` //user id
 	DisplayName string `gorm:"column:display_name;type:varchar(64);not null" json:"display_name"`
+	IsGuest     bool   `gorm:"column:is_guest;type:tinyint(1);not null;default:0" json:"is_guest"`
+	Role        string `gorm:"column:role;type:varchar(16);not null;default:attendee" json:"role"`
 }
 
+// Participant roles, from most to least privileged. The room creator is
+// always the host.
+const (
+	RoomRoleHost      = "host"
+	RoomRoleCoHost    = "cohost"
+	RoomRolePresenter = "presenter"
+	RoomRoleAttendee  = "attendee"
+)
+
 func (table *RoomUser) TableName() string {
 	return "room_user"
 }
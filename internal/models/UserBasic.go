@@ -5,9 +5,22 @@ import "gorm.io/gorm"
 type UserBasic struct {
 	gorm.Model
 	Username string `gorm:"column:username;type:varchar(100);uniqueIndex;not null" json:"username"`
-	Password string `gorm:"column:password;type:varchar(64);not null" json:"password"`
-	Sdp      string `gorm:"column:sdp;type:text" json:"sdp"` //sdp-p-p
+	Password string `gorm:"column:password;type:varchar(255);not null" json:"-"` // bcrypt hash, legacy rows may hold md5
+	Sdp      string `gorm:"column:sdp;type:text" json:"sdp"`                     //sdp-p-p
+	Role     string `gorm:"column:role;type:varchar(16);not null;default:user" json:"role"`
+	Disabled bool   `gorm:"column:disabled;type:tinyint(1);not null;default:0" json:"disabled"`
+	// CalendarToken is the SHA-256 of the secret in the user's calendar feed URL.
+	CalendarToken string `gorm:"column:calendar_token;type:varchar(64);index" json:"-"`
+}
+
+const (
+	UserRoleUser  = "user"
+	UserRoleAdmin = "admin"
+	UserRoleGuest = "guest" // created by guest join, cannot log in
+)
 
+func (table *UserBasic) IsAdmin() bool {
+	return table.Role == UserRoleAdmin
 }
 
 func (table *UserBasic) TableName() string {
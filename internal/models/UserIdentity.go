@@ -0,0 +1,16 @@
+package models
+
+import "gorm.io/gorm"
+
+// UserIdentity links an external OpenID Connect subject to a local user.
+type UserIdentity struct {
+	gorm.Model
+	Uid     uint   `gorm:"column:uid;type:int(11);not null;index" json:"uid"`
+	Issuer  string `gorm:"column:issuer;type:varchar(255);not null;uniqueIndex:idx_issuer_subject" json:"issuer"`
+	Subject string `gorm:"column:subject;type:varchar(255);not null;uniqueIndex:idx_issuer_subject" json:"subject"`
+	Email   string `gorm:"column:email;type:varchar(255)" json:"email"`
+}
+
+func (table *UserIdentity) TableName() string {
+	return "user_identity"
+}
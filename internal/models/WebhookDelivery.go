@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is one event queued for one subscription. The payload is
+// rendered once so every retry sends the same body.
+type WebhookDelivery struct {
+	gorm.Model
+	SubscriptionID uint       `gorm:"column:subscription_id;type:int(11);not null;index" json:"subscription_id"`
+	EventID        string     `gorm:"column:event_id;type:varchar(36);not null;index" json:"event_id"`
+	Event          string     `gorm:"column:event;type:varchar(64);not null" json:"event"`
+	Payload        string     `gorm:"column:payload;type:mediumtext;not null" json:"payload"`
+	Status         string     `gorm:"column:status;type:varchar(16);not null;default:pending;index:idx_delivery_due" json:"status"`
+	Attempts       int        `gorm:"column:attempts;type:int(11);not null;default:0" json:"attempts"`
+	NextAttemptAt  time.Time  `gorm:"column:next_attempt_at;type:datetime;not null;index:idx_delivery_due" json:"next_attempt_at"`
+	LastStatusCode int        `gorm:"column:last_status_code;type:int(11)" json:"last_status_code"`
+	LastError      string     `gorm:"column:last_error;type:varchar(512)" json:"last_error"`
+	DeliveredAt    *time.Time `gorm:"column:delivered_at;type:datetime" json:"delivered_at"`
+}
+
+func (table *WebhookDelivery) TableName() string {
+	return "webhook_delivery"
+}
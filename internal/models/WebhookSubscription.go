@@ -0,0 +1,18 @@
+package models
+
+import "gorm.io/gorm"
+
+// WebhookSubscription receives signed POSTs for the events in its filter.
+type WebhookSubscription struct {
+	gorm.Model
+	URL         string `gorm:"column:url;type:varchar(512);not null" json:"url"`
+	Secret      string `gorm:"column:secret;type:varchar(128);not null" json:"-"`
+	Events      string `gorm:"column:events;type:varchar(512);not null;default:*" json:"events"` //comma-separated, * for all
+	Description string `gorm:"column:description;type:varchar(255)" json:"description"`
+	Active      bool   `gorm:"column:active;type:tinyint(1);not null;default:1" json:"active"`
+	CreateID    uint   `gorm:"column:create_id;type:int(11);not null" json:"create_id"`
+}
+
+func (table *WebhookSubscription) TableName() string {
+	return "webhook_subscription"
+}
@@ -23,7 +23,7 @@ func NewDB() {
 		panic("failed to connect database: " + err.Error())
 	}
 
-	db.AutoMigrate(&RoomBasic{}, &RoomUser{}, &UserBasic{}, &RoomScreenShare{})
+	db.AutoMigrate(&RoomBasic{}, &RoomUser{}, &UserBasic{}, &RoomScreenShare{}, &RefreshToken{}, &UserIdentity{}, &RoomLobby{}, &RoomBan{}, &RoomOccurrence{}, &WebhookSubscription{}, &WebhookDelivery{}, &ChatMessage{}, &Poll{}, &PollOption{}, &PollVote{}, &RoomQuestion{}, &QuestionUpvote{}, &ParticipantSession{})
 
 	DB = db
 }
@@ -0,0 +1,303 @@
+// Package recurrence implements the subset of RFC 5545 recurrence rules used
+// for recurring meetings: FREQ=DAILY|WEEKLY|MONTHLY with INTERVAL, COUNT,
+// UNTIL, BYDAY (weekly) and BYMONTHDAY (monthly).
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+)
+
+// maxPeriods bounds expansion of rules without COUNT or UNTIL.
+const maxPeriods = 100000
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// Rule is a parsed RRULE. Occurrences are anchored at the series start
+// (DTSTART) passed to the expansion methods.
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []time.Weekday
+	ByMonthDay []int
+}
+
+// Parse reads a rule such as "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;COUNT=10".
+// An optional "RRULE:" prefix is accepted.
+func Parse(s string) (*Rule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return nil, errors.New("rrule is empty")
+	}
+	r := &Rule{Interval: 1}
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("rrule: malformed part %q", part)
+		}
+		key = strings.ToUpper(strings.TrimSpace(key))
+		value = strings.ToUpper(strings.TrimSpace(value))
+		switch key {
+		case "FREQ":
+			switch f := Frequency(value); f {
+			case Daily, Weekly, Monthly:
+				r.Freq = f
+			default:
+				return nil, fmt.Errorf("rrule: unsupported FREQ %q", value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("rrule: invalid INTERVAL %q", value)
+			}
+			r.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("rrule: invalid COUNT %q", value)
+			}
+			r.Count = n
+		case "UNTIL":
+			t, err := parseUntil(value)
+			if err != nil {
+				return nil, err
+			}
+			r.Until = t
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				day, ok := weekdayCodes[code]
+				if !ok {
+					return nil, fmt.Errorf("rrule: unsupported BYDAY %q", code)
+				}
+				r.ByDay = append(r.ByDay, day)
+			}
+		case "BYMONTHDAY":
+			for _, v := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(v)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("rrule: invalid BYMONTHDAY %q", v)
+				}
+				r.ByMonthDay = append(r.ByMonthDay, n)
+			}
+		default:
+			return nil, fmt.Errorf("rrule: unsupported part %s", key)
+		}
+	}
+
+	if r.Freq == "" {
+		return nil, errors.New("rrule: FREQ is required")
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return nil, errors.New("rrule: COUNT and UNTIL are mutually exclusive")
+	}
+	if len(r.ByDay) > 0 && r.Freq != Weekly {
+		return nil, errors.New("rrule: BYDAY is only supported with FREQ=WEEKLY")
+	}
+	if len(r.ByMonthDay) > 0 && r.Freq != Monthly {
+		return nil, errors.New("rrule: BYMONTHDAY is only supported with FREQ=MONTHLY")
+	}
+	sort.Slice(r.ByDay, func(i, j int) bool {
+		return mondayIndex(r.ByDay[i]) < mondayIndex(r.ByDay[j])
+	})
+	return r, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		loc := time.Local
+		if strings.HasSuffix(layout, "Z") {
+			loc = time.UTC
+		}
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			if layout == "20060102" {
+				// A date-only UNTIL includes the whole day.
+				t = t.Add(24*time.Hour - time.Second)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("rrule: invalid UNTIL %q", value)
+}
+
+// String formats the rule in canonical RRULE form without the prefix.
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, 0, len(r.ByDay))
+		for _, day := range r.ByDay {
+			codes = append(codes, strings.ToUpper(day.String()[:2]))
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, 0, len(r.ByMonthDay))
+		for _, d := range r.ByMonthDay {
+			days = append(days, strconv.Itoa(d))
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Between returns the start times of occurrences in [from, to), in order.
+func (r *Rule) Between(dtstart, from, to time.Time) []time.Time {
+	var out []time.Time
+	r.each(dtstart, func(t time.Time) bool {
+		if !t.Before(to) {
+			return false
+		}
+		if !t.Before(from) {
+			out = append(out, t)
+		}
+		return true
+	})
+	return out
+}
+
+// Includes reports whether t is the start of an occurrence of the series.
+func (r *Rule) Includes(dtstart, t time.Time) bool {
+	found := false
+	r.each(dtstart, func(c time.Time) bool {
+		if c.Equal(t) {
+			found = true
+		}
+		return c.Before(t)
+	})
+	return found
+}
+
+// Last returns the start of the final occurrence, or false when the series
+// is unbounded.
+func (r *Rule) Last(dtstart time.Time) (time.Time, bool) {
+	if r.Count == 0 && r.Until.IsZero() {
+		return time.Time{}, false
+	}
+	var last time.Time
+	r.each(dtstart, func(t time.Time) bool {
+		last = t
+		return true
+	})
+	return last, !last.IsZero()
+}
+
+// each calls fn for every occurrence in order until fn returns false or the
+// series ends. DTSTART is always the first occurrence.
+func (r *Rule) each(dtstart time.Time, fn func(time.Time) bool) {
+	emitted := 0
+	emit := func(t time.Time) bool {
+		if t.Before(dtstart) {
+			return true
+		}
+		if !r.Until.IsZero() && t.After(r.Until) {
+			return false
+		}
+		if r.Count > 0 && emitted >= r.Count {
+			return false
+		}
+		emitted++
+		return fn(t)
+	}
+
+	if !emit(dtstart) {
+		return
+	}
+	for period := 0; period < maxPeriods; period++ {
+		for _, t := range r.periodStarts(dtstart, period) {
+			if t.Equal(dtstart) {
+				continue
+			}
+			if !emit(t) {
+				return
+			}
+		}
+	}
+}
+
+// periodStarts lists the candidate starts within the n-th period (day, week
+// or month) of the series, keeping the wall-clock time of dtstart.
+func (r *Rule) periodStarts(dtstart time.Time, n int) []time.Time {
+	step := n * r.Interval
+	switch r.Freq {
+	case Daily:
+		return []time.Time{dtstart.AddDate(0, 0, step)}
+	case Weekly:
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []time.Weekday{dtstart.Weekday()}
+		}
+		weekStart := dtstart.AddDate(0, 0, 7*step-mondayIndex(dtstart.Weekday()))
+		out := make([]time.Time, 0, len(days))
+		for _, day := range days {
+			out = append(out, weekStart.AddDate(0, 0, mondayIndex(day)))
+		}
+		return out
+	case Monthly:
+		first := time.Date(dtstart.Year(), dtstart.Month()+time.Month(step), 1,
+			dtstart.Hour(), dtstart.Minute(), dtstart.Second(), dtstart.Nanosecond(), dtstart.Location())
+		length := first.AddDate(0, 1, -1).Day()
+		monthDays := r.ByMonthDay
+		if len(monthDays) == 0 {
+			monthDays = []int{dtstart.Day()}
+		}
+		days := make([]int, 0, len(monthDays))
+		for _, d := range monthDays {
+			if d < 0 {
+				d = length + 1 + d
+			}
+			// Days that do not exist in this month are skipped (RFC 5545).
+			if d >= 1 && d <= length {
+				days = append(days, d)
+			}
+		}
+		sort.Ints(days)
+		out := make([]time.Time, 0, len(days))
+		for i, d := range days {
+			if i > 0 && days[i-1] == d {
+				continue
+			}
+			out = append(out, first.AddDate(0, 0, d-1))
+		}
+		return out
+	}
+	return nil
+}
+
+// mondayIndex numbers weekdays from Monday (0) to Sunday (6), the RFC 5545
+// default week start.
+func mondayIndex(d time.Weekday) int {
+	return (int(d) + 6) % 7
+}
@@ -0,0 +1,111 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+)
+
+func mustParse(t *testing.T, s string) *Rule {
+	t.Helper()
+	r, err := Parse(s)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", s, err)
+	}
+	return r
+}
+
+func dates(ts []time.Time) []string {
+	out := make([]string, 0, len(ts))
+	for _, t := range ts {
+		out = append(out, t.Format("2006-01-02 15:04"))
+	}
+	return out
+}
+
+func assertDates(t *testing.T, got []time.Time, want ...string) {
+	t.Helper()
+	g := dates(got)
+	if len(g) != len(want) {
+		t.Fatalf("got %v, want %v", g, want)
+	}
+	for i := range want {
+		if g[i] != want[i] {
+			t.Fatalf("got %v, want %v", g, want)
+		}
+	}
+}
+
+// 2026-10-07 is a Wednesday.
+var dtstart = time.Date(2026, 10, 7, 9, 30, 0, 0, time.UTC)
+
+func TestDailyCount(t *testing.T) {
+	r := mustParse(t, "RRULE:FREQ=DAILY;INTERVAL=2;COUNT=3")
+	assertDates(t, r.Between(dtstart, dtstart, dtstart.AddDate(1, 0, 0)),
+		"2026-10-07 09:30", "2026-10-09 09:30", "2026-10-11 09:30")
+	last, ok := r.Last(dtstart)
+	if !ok || !last.Equal(time.Date(2026, 10, 11, 9, 30, 0, 0, time.UTC)) {
+		t.Fatalf("Last = %v, %v", last, ok)
+	}
+}
+
+func TestWeeklyByDayUntil(t *testing.T) {
+	r := mustParse(t, "FREQ=WEEKLY;BYDAY=FR,MO;UNTIL=20261019")
+	assertDates(t, r.Between(dtstart, dtstart, dtstart.AddDate(0, 1, 0)),
+		"2026-10-07 09:30", "2026-10-09 09:30", "2026-10-12 09:30", "2026-10-16 09:30", "2026-10-19 09:30")
+}
+
+func TestWeeklyIntervalWindow(t *testing.T) {
+	r := mustParse(t, "FREQ=WEEKLY;INTERVAL=2")
+	from := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC)
+	assertDates(t, r.Between(dtstart, from, to), "2026-11-04 09:30", "2026-11-18 09:30")
+	if _, ok := r.Last(dtstart); ok {
+		t.Fatal("unbounded rule reported a last occurrence")
+	}
+}
+
+func TestMonthlySkipsMissingDays(t *testing.T) {
+	start := time.Date(2027, 1, 31, 18, 0, 0, 0, time.UTC)
+	r := mustParse(t, "FREQ=MONTHLY;COUNT=3")
+	assertDates(t, r.Between(start, start, start.AddDate(1, 0, 0)),
+		"2027-01-31 18:00", "2027-03-31 18:00", "2027-05-31 18:00")
+
+	r = mustParse(t, "FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=3")
+	assertDates(t, r.Between(start, start, start.AddDate(1, 0, 0)),
+		"2027-01-31 18:00", "2027-02-28 18:00", "2027-03-31 18:00")
+}
+
+func TestIncludes(t *testing.T) {
+	r := mustParse(t, "FREQ=WEEKLY;BYDAY=WE")
+	if !r.Includes(dtstart, dtstart.AddDate(0, 0, 14)) {
+		t.Fatal("expected occurrence two weeks later")
+	}
+	if r.Includes(dtstart, dtstart.AddDate(0, 0, 15)) {
+		t.Fatal("unexpected occurrence on a Thursday")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=YEARLY",
+		"FREQ=DAILY;COUNT=2;UNTIL=20261231",
+		"FREQ=DAILY;BYDAY=MO",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=MONTHLY;BYMONTHDAY=0",
+		"FREQ=DAILY;BYHOUR=9",
+	} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Parse(%q) succeeded", s)
+		}
+	}
+}
+
+func TestStringRoundTrip(t *testing.T) {
+	r := mustParse(t, "freq=weekly;byday=we,mo;interval=2;until=20261231T000000Z")
+	want := "FREQ=WEEKLY;INTERVAL=2;UNTIL=20261231T000000Z;BYDAY=MO,WE"
+	if got := r.String(); got != want {
+		t.Fatalf("String() = %q, want %q", got, want)
+	}
+}
@@ -9,8 +9,11 @@ package main
 // @in header
 // @name Authorization
 import (
+	"GoMeetings/internal/helper"
 	"GoMeetings/internal/models"
 	"GoMeetings/internal/server/router"
+	"GoMeetings/internal/server/service"
+	"context"
 	"log"
 
 	_ "GoMeetings/docs"
@@ -20,7 +23,18 @@ import (
 
 func main() {
 	godotenv.Load()
+	if err := helper.LoadSigningKeys(); err != nil {
+		log.Fatalln("load jwt keys error.", err)
+	}
 	models.NewDB()
+	service.BootstrapAdmins()
+	// The broker replaces the signaling hub, so it must be in place before
+	// any background worker can reach it.
+	if err := service.StartSignalBroker(); err != nil {
+		log.Fatalln("signal broker error.", err)
+	}
+	service.StartWebhookWorker(context.Background())
+	service.StartMeetingScheduler(context.Background())
 	e := router.Router()
 	err := e.Run()
 	if err != nil {
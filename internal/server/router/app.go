@@ -12,6 +12,7 @@ import (
 func Router() *gin.Engine {
 	r := gin.Default()
 	r.GET("/ping", pingHandler)
+	r.GET("/.well-known/jwks.json", service.WellKnownJWKS)
 
 	r.Use(middlewares.Cors())
 
@@ -24,11 +25,21 @@ func Router() *gin.Engine {
 	// WebRTC signaling websocket (no auth required to keep demo simple)
 	r.GET("/ws/p2p/:roomIdentity/:userIdentity", service.SignalWebsocket)
 
+	// Calendar subscription feed, authenticated by the secret in the URL
+	r.GET("/calendar/:token", service.CalendarFeed)
+
 	publicAuth := r.Group("/auth")
 	publicAuth.POST("/user/login", service.UserLogin)
 	publicAuth.POST("/user/register", service.UserRegister)
+	publicAuth.POST("/user/refresh", service.UserRefresh)
+	publicAuth.GET("/oidc/login", service.OIDCLogin)
+	publicAuth.GET("/oidc/callback", service.OIDCCallback)
+	publicAuth.POST("/guest/join", service.GuestJoin)
+	publicAuth.GET("/room/lookup", service.RoomLookup)
 
 	auth := r.Group("/auth", middlewares.Auth())
+	auth.POST("/user/logout", service.UserLogout)
+	auth.POST("/user/calendar-token", service.UserCalendarToken)
 
 	room := auth.Group("/room")
 	room.GET("/list", service.RoomList)
@@ -36,12 +47,73 @@ func Router() *gin.Engine {
 	room.PUT("/edit", service.RoomEdit)
 	room.DELETE("/delete", service.RoomDelete)
 	room.POST("/join", service.RoomJoin)
+	room.POST("/join-by-code", service.RoomJoinByCode)
 	room.POST("/leave", service.RoomLeave)
 	room.GET("/members", service.RoomMembers)
 	room.GET("/user-rooms", service.RoomUserRooms)
-	room.POST("/share/start", service.RoomShareStart)
-	room.POST("/share/stop", service.RoomShareStop)
-	room.GET("/share/status", service.RoomShareStatus)
+	room.GET("/ics", service.RoomICS)
+	room.GET("/lobby", service.RoomLobbyList)
+	room.POST("/lobby/admit", service.RoomLobbyAdmit)
+	room.POST("/lobby/deny", service.RoomLobbyDeny)
+	room.POST("/role", service.RoomRoleUpdate)
+	room.POST("/kick", service.RoomKick)
+	room.POST("/ban", service.RoomBanUser)
+	room.POST("/unban", service.RoomUnbanUser)
+	room.POST("/mute", service.RoomMute)
+	room.POST("/lock", service.RoomLock)
+	room.POST("/start", service.RoomStart)
+	room.POST("/end", service.RoomEnd)
+	room.POST("/cancel", service.RoomCancel)
+	room.POST("/extend", service.RoomExtend)
+	room.POST("/hand/lower", service.RoomHandLower)
+	room.GET("/attendance", service.RoomAttendance)
+	room.PUT("/occurrence/edit", service.RoomOccurrenceEdit)
+	room.POST("/occurrence/cancel", service.RoomOccurrenceCancel)
+	room.POST("/breakout/create", service.RoomBreakoutCreate)
+	room.POST("/breakout/assign", service.RoomBreakoutAssign)
+	room.POST("/breakout/auto-assign", service.RoomBreakoutAutoAssign)
+	room.POST("/breakout/countdown", service.RoomBreakoutCountdown)
+	room.POST("/breakout/return", service.RoomBreakoutReturn)
+	room.POST("/breakout/visit", service.RoomBreakoutVisit)
+	room.GET("/engagement/export", service.RoomEngagementExport)
+
+	// Screen sharing is also available to room-scoped guest tokens.
+	share := r.Group("/auth/room/share", middlewares.AuthAllowGuest())
+	share.POST("/start", service.RoomShareStart)
+	share.POST("/stop", service.RoomShareStop)
+	share.GET("/status", service.RoomShareStatus)
+
+	chat := r.Group("/auth/room/chat", middlewares.AuthAllowGuest())
+	chat.GET("/history", service.RoomChatHistory)
+	chat.POST("/edit", service.RoomChatEdit)
+	chat.POST("/delete", service.RoomChatDelete)
+
+	poll := r.Group("/auth/room/poll", middlewares.AuthAllowGuest())
+	poll.GET("/list", service.RoomPollList)
+	poll.POST("/create", service.RoomPollCreate)
+	poll.POST("/vote", service.RoomPollVote)
+	poll.POST("/close", service.RoomPollClose)
+
+	question := r.Group("/auth/room/question", middlewares.AuthAllowGuest())
+	question.GET("/list", service.RoomQuestionList)
+	question.POST("/ask", service.RoomQuestionAsk)
+	question.POST("/upvote", service.RoomQuestionUpvote)
+	question.POST("/answer", service.RoomQuestionAnswer)
+
+	// Guests may see the breakouts of their room.
+	r.GET("/auth/room/breakout/list", middlewares.AuthAllowGuest(), service.RoomBreakoutList)
+
+	admin := r.Group("/admin", middlewares.Auth(), middlewares.RequireAdmin())
+	admin.GET("/users", service.AdminUserList)
+	admin.POST("/users/disable", service.AdminUserDisable)
+	admin.GET("/rooms", service.AdminRoomList)
+	admin.POST("/rooms/end", service.AdminRoomEnd)
+	admin.GET("/signal/occupancy", service.AdminSignalOccupancy)
+	admin.GET("/webhooks", service.AdminWebhookList)
+	admin.POST("/webhooks", service.AdminWebhookCreate)
+	admin.POST("/webhooks/delete", service.AdminWebhookDelete)
+	admin.GET("/webhooks/deliveries", service.AdminWebhookDeliveries)
+	admin.POST("/webhooks/deliveries/retry", service.AdminWebhookRetry)
 
 	return r
 }
@@ -0,0 +1,354 @@
+package service
+
+import (
+	"GoMeetings/internal/helper"
+	"GoMeetings/internal/models"
+	"GoMeetings/internal/webhook"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// BootstrapAdmins promotes the comma-separated ADMIN_USERNAMES to admins so a
+// fresh deployment has someone who can reach the /admin routes.
+func BootstrapAdmins() {
+	for _, name := range adminUsernames() {
+		res := models.DB.Model(&models.UserBasic{}).Where("username = ?", name).Update("role", models.UserRoleAdmin)
+		if res.Error != nil {
+			log.Printf("admin: promote %s failed: %v", name, res.Error)
+		} else if res.RowsAffected == 0 {
+			log.Printf("admin: user %s not found, not promoted", name)
+		}
+	}
+}
+
+// adminUsernames lists the non-empty names of ADMIN_USERNAMES.
+func adminUsernames() []string {
+	var names []string
+	for _, name := range strings.Split(os.Getenv("ADMIN_USERNAMES"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func isAdminUsername(name string) bool {
+	for _, admin := range adminUsernames() {
+		if strings.EqualFold(admin, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// AdminUserList godoc
+// @Summary Admin: list users
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param page query int false "Page number"
+// @Param size query int false "Page size"
+// @Param keyword query string false "Username filter"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/users [get]
+func AdminUserList(c *gin.Context) {
+	req := AdminListRequest{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.Size <= 0 {
+		req.Size = 20
+	}
+
+	query := models.DB.Model(&models.UserBasic{})
+	if req.Keyword != "" {
+		query = query.Where("username LIKE ?", "%"+req.Keyword+"%")
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	var users []models.UserBasic
+	if err := query.Order("id asc").
+		Limit(req.Size).Offset((req.Page - 1) * req.Size).
+		Find(&users).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+
+	list := make([]AdminUserItem, 0, len(users))
+	for _, u := range users {
+		list = append(list, AdminUserItem{
+			ID:        u.ID,
+			Username:  u.Username,
+			Role:      u.Role,
+			Disabled:  u.Disabled,
+			CreatedAt: u.CreatedAt.UnixMilli(),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": AdminUserListReply{Total: total, List: list}})
+}
+
+// AdminUserDisable godoc
+// @Summary Admin: disable or enable a user
+// @Description Disabling revokes all tokens and closes the user's signaling connections
+// @Tags Admin
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param user_id formData integer true "User ID"
+// @Param disabled formData boolean true "Disable (true) or enable (false)"
+// @Success 200 {object} map[string]string
+// @Router /admin/users/disable [post]
+func AdminUserDisable(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	req := AdminUserDisableRequest{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	if req.UserID == uc.Id {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "cannot disable yourself"})
+		return
+	}
+
+	var user models.UserBasic
+	if err := models.DB.First(&user, req.UserID).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "user not found"})
+		return
+	}
+	if err := models.DB.Model(&user).Update("disabled", req.Disabled).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	if req.Disabled {
+		if err := revokeUserTokens(user.ID); err != nil {
+			c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+			return
+		}
+		wsHub.disconnectUser(user.ID, closeAccountDisabled, "account disabled")
+		c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "user disabled"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "user enabled"})
+}
+
+// AdminRoomList godoc
+// @Summary Admin: list all rooms
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param page query int false "Page number"
+// @Param size query int false "Page size"
+// @Param keyword query string false "Room name filter"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/rooms [get]
+func AdminRoomList(c *gin.Context) {
+	req := AdminListRequest{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.Size <= 0 {
+		req.Size = 20
+	}
+
+	query := models.DB.Model(&models.RoomBasic{})
+	if req.Keyword != "" {
+		query = query.Where("name LIKE ?", "%"+req.Keyword+"%")
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	var rooms []models.RoomBasic
+	if err := query.Order("created_at desc").
+		Limit(req.Size).Offset((req.Page - 1) * req.Size).
+		Find(&rooms).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+
+	list := make([]AdminRoomItem, 0, len(rooms))
+	for _, room := range rooms {
+		list = append(list, AdminRoomItem{
+			Identity:  room.Identify,
+			Name:      room.Name,
+			BeginAt:   room.BeginAt,
+			EndAt:     room.EndAt,
+			CreateID:  room.CreateID,
+			ShortCode: room.ShortCode,
+			LivePeers: wsHub.roomPeerCount(room.Identify),
+			CreatedAt: room.CreatedAt.UnixMilli(),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": AdminRoomListReply{Total: total, List: list}})
+}
+
+// AdminRoomEnd godoc
+// @Summary Admin: force-end a room
+// @Description Moves the end time to now, stops screen sharing and disconnects all peers
+// @Tags Admin
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param identity formData string true "Room identity"
+// @Success 200 {object} map[string]string
+// @Router /admin/rooms/end [post]
+func AdminRoomEnd(c *gin.Context) {
+	req := AdminRoomEndRequest{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	var room models.RoomBasic
+	if err := models.DB.Where("identify = ?", req.Identity).First(&room).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "room not found"})
+		return
+	}
+	if err := forceEndRoom(&room, "ended_by_admin"); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "room ended"})
+}
+
+// AdminSignalOccupancy godoc
+// @Summary Admin: live signaling occupancy
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/signal/occupancy [get]
+func AdminSignalOccupancy(c *gin.Context) {
+	snapshot := wsHub.snapshot()
+	reply := SignalOccupancyReply{Rooms: make([]SignalRoomOccupancy, 0, len(snapshot))}
+	for roomIdentity, peers := range snapshot {
+		sort.Strings(peers)
+		reply.Rooms = append(reply.Rooms, SignalRoomOccupancy{Identity: roomIdentity, Peers: peers})
+		reply.TotalPeers += len(peers)
+	}
+	sort.Slice(reply.Rooms, func(i, j int) bool { return reply.Rooms[i].Identity < reply.Rooms[j].Identity })
+	reply.TotalRooms = len(reply.Rooms)
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": reply})
+}
+
+// forceEndRoom closes the meeting right away: the end time is pulled in so
+// nobody can rejoin, active screen shares are stopped and peers disconnected.
+// For a recurring room only the running occurrence is cut short.
+func forceEndRoom(room *models.RoomBasic, reason string) error {
+	now := time.Now()
+	if room.RRule != "" {
+		occ, ok, err := upcomingRoomOccurrence(room, now)
+		if err != nil {
+			return err
+		}
+		if ok && !occ.BeginAt.After(now.Add(roomEarlyJoinWindow)) {
+			if occ.BeginAt.After(now) {
+				occ.BeginAt = now
+			}
+			occ.EndAt = now
+			if err := saveOccurrenceOverride(room, occ); err != nil {
+				return err
+			}
+		}
+	} else if room.EndAt.After(now) {
+		update := map[string]interface{}{
+			"end_at":   now,
+			"sequence": gorm.Expr("sequence + 1"),
+		}
+		// Ended during the early-join window: keep begin_at <= end_at.
+		if room.BeginAt.After(now) {
+			update["begin_at"] = now
+		}
+		if err := models.DB.Model(room).Updates(update).Error; err != nil {
+			return err
+		}
+		if room.BeginAt.After(now) {
+			room.BeginAt = now
+		}
+		room.EndAt = now
+		room.Sequence++
+	}
+
+	return closeMeeting(room, reason, now)
+}
+
+// closeMeeting stops the active screen shares of the room, announces
+// meeting_ended and disconnects every peer, breakout rooms included.
+func closeMeeting(room *models.RoomBasic, reason string, now time.Time) error {
+	breakouts, err := listBreakouts(room)
+	if err != nil {
+		return err
+	}
+	if err := stopRoomShares(room, reason); err != nil {
+		return err
+	}
+	for i := range breakouts {
+		if err := stopRoomShares(&breakouts[i], reason); err != nil {
+			return err
+		}
+	}
+	if err := models.DB.Model(room).Update("ended_at", now).Error; err != nil {
+		return err
+	}
+	room.EndedAt = &now
+	rids := []uint{room.ID}
+	for _, b := range breakouts {
+		rids = append(rids, b.ID)
+	}
+	if err := closeOpenSessions(rids, now); err != nil {
+		log.Printf("room %s: close attendance sessions: %v", room.Identify, err)
+	}
+	if err := finishMeetingStatus(room, now); err != nil {
+		log.Printf("room %s: update status: %v", room.Identify, err)
+	}
+
+	notifyRoomEvent(room.Identify, "meeting_ended", map[string]interface{}{
+		"reason":   reason,
+		"ended_at": now.UnixMilli(),
+	})
+	data := roomWebhookData(room)
+	data["reason"] = reason
+	data["ended_at"] = now.UnixMilli()
+	emitWebhookEvent(webhook.MeetingEnded, data)
+	wsHub.closeRoom(room.Identify, closeMeetingEnded, "meeting ended")
+	for i := range breakouts {
+		wsHub.closeRoom(breakouts[i].Identify, closeMeetingEnded, "meeting ended")
+	}
+	return nil
+}
+
+func stopRoomShares(room *models.RoomBasic, reason string) error {
+	var shares []models.RoomScreenShare
+	if err := models.DB.Where("rid = ? AND active = ?", room.ID, true).Find(&shares).Error; err != nil {
+		return err
+	}
+	for i := range shares {
+		if err := deactivateScreenShare(&shares[i]); err != nil {
+			return err
+		}
+		notifyScreenShareEvent(room.Identify, "screen_share_stopped", map[string]interface{}{
+			"owner_id": shares[i].OwnerUid,
+			"reason":   reason,
+			"ended_at": shares[i].EndedAt,
+		})
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package service
+
+import (
+	"GoMeetings/internal/helper"
+	"GoMeetings/internal/models"
+	"GoMeetings/internal/webhook"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recordPeerJoined opens an attendance session for the peer's connection to
+// the room and emits participant.joined.
+func recordPeerJoined(roomIdentity string, peer *peerConn) {
+	emitParticipantWebhook(webhook.ParticipantJoined, roomIdentity, peer)
+
+	var room models.RoomBasic
+	if err := models.DB.Where("identify = ?", roomIdentity).First(&room).Error; err != nil {
+		return
+	}
+	session := models.ParticipantSession{
+		Rid:          room.ID,
+		Uid:          peer.uid,
+		UserIdentity: peer.user,
+		DisplayName:  peer.user,
+		JoinedAt:     time.Now(),
+	}
+	var membership models.RoomUser
+	if err := models.DB.Where("rid = ? AND uid = ?", room.ID, peer.uid).First(&membership).Error; err == nil {
+		session.DisplayName = resolveDisplayName(&membership, peer.user)
+		session.IsGuest = membership.IsGuest
+	}
+	if err := models.DB.Create(&session).Error; err != nil {
+		log.Printf("attendance: open session for %s in %s: %v", peer.user, roomIdentity, err)
+		return
+	}
+	peer.roomMu.Lock()
+	peer.sessionID = session.ID
+	peer.roomMu.Unlock()
+}
+
+// recordPeerLeft closes the peer's open attendance session and emits
+// participant.left.
+func recordPeerLeft(roomIdentity string, peer *peerConn) {
+	emitParticipantWebhook(webhook.ParticipantLeft, roomIdentity, peer)
+
+	peer.roomMu.Lock()
+	sessionID := peer.sessionID
+	peer.sessionID = 0
+	peer.roomMu.Unlock()
+	if sessionID == 0 {
+		return
+	}
+	if err := models.DB.Model(&models.ParticipantSession{}).
+		Where("id = ? AND left_at IS NULL", sessionID).
+		Update("left_at", time.Now()).Error; err != nil {
+		log.Printf("attendance: close session %d: %v", sessionID, err)
+	}
+}
+
+// closeOpenSessions ends the sessions still open in the rooms, so ones whose
+// node went away without recording the leave stop counting.
+func closeOpenSessions(rids []uint, now time.Time) error {
+	return models.DB.Model(&models.ParticipantSession{}).
+		Where("rid IN ? AND left_at IS NULL", rids).
+		Update("left_at", now).Error
+}
+
+// RoomAttendance godoc
+// @Summary Attendance report
+// @Description Per participant: first join, last leave, total connected time and reconnects. Time spent in breakout rooms counts toward the main room.
+// @Tags Room
+// @Security BearerAuth
+// @Produce json
+// @Produce text/csv
+// @Param identity query string true "Room identity"
+// @Param format query string false "json (default) or csv"
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/room/attendance [get]
+func RoomAttendance(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	room, _, ok := loadRoomAndMembership(c, uc, c.Query("identity"))
+	if !ok {
+		return
+	}
+	if !hasRoomPermission(room, uc.Id, permViewAttendance) {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "no permission"})
+		return
+	}
+
+	rids := []uint{room.ID}
+	breakouts, err := listBreakouts(room)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	for _, b := range breakouts {
+		rids = append(rids, b.ID)
+	}
+	var sessions []models.ParticipantSession
+	if err := models.DB.Where("rid IN ?", rids).Order("joined_at asc").Find(&sessions).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	report := attendanceReport(sessions, time.Now(), room.EndedAt)
+
+	switch c.DefaultQuery("format", "json") {
+	case "json":
+		c.JSON(http.StatusOK, gin.H{"code": 200, "data": report})
+	case "csv":
+		body, err := attendanceCSV(report)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-attendance.csv"`, room.Identify))
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", body)
+	default:
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "format must be json or csv"})
+	}
+}
+
+// attendanceReport folds sessions (ordered by join time) into one row per
+// user. Open sessions count up to now, or up to endedAt when the meeting
+// ended after they started (their node went away before closing them).
+// Overlapping sessions, e.g. during a breakout move or from a second device,
+// are only counted once, and only a session starting after a gap counts as
+// a reconnect.
+func attendanceReport(sessions []models.ParticipantSession, now time.Time, endedAt *time.Time) []AttendanceItem {
+	type span struct{ from, to time.Time }
+	byUser := make(map[uint]*AttendanceItem)
+	spans := make(map[uint][]span)
+	order := make([]uint, 0)
+	for _, s := range sessions {
+		item, ok := byUser[s.Uid]
+		if !ok {
+			item = &AttendanceItem{UserID: s.Uid, FirstJoin: s.JoinedAt.UnixMilli()}
+			byUser[s.Uid] = item
+			order = append(order, s.Uid)
+		}
+		item.DisplayName = s.DisplayName
+		item.Guest = s.IsGuest
+		item.Sessions++
+
+		end := now
+		leftAt := s.LeftAt
+		if leftAt == nil && endedAt != nil && !endedAt.Before(s.JoinedAt) {
+			leftAt = endedAt
+		}
+		if leftAt != nil {
+			end = *leftAt
+			if left := leftAt.UnixMilli(); left > item.LastLeave {
+				item.LastLeave = left
+			}
+		} else {
+			item.Connected = true
+		}
+		spans[s.Uid] = append(spans[s.Uid], span{s.JoinedAt, end})
+	}
+
+	report := make([]AttendanceItem, 0, len(order))
+	for _, uid := range order {
+		item := byUser[uid]
+		var total time.Duration
+		var cur span
+		for i, sp := range spans[uid] {
+			switch {
+			case i == 0:
+				cur = sp
+			case !sp.from.After(cur.to):
+				if sp.to.After(cur.to) {
+					cur.to = sp.to
+				}
+			default:
+				total += cur.to.Sub(cur.from)
+				cur = sp
+				item.Reconnects++
+			}
+		}
+		total += cur.to.Sub(cur.from)
+		item.TotalSeconds = int64(total / time.Second)
+		if item.Connected {
+			item.LastLeave = 0
+		}
+		report = append(report, *item)
+	}
+	sort.SliceStable(report, func(i, j int) bool { return report[i].FirstJoin < report[j].FirstJoin })
+	return report
+}
+
+func attendanceCSV(report []AttendanceItem) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	rows := [][]string{{"user_id", "display_name", "guest", "first_join", "last_leave", "total_seconds", "sessions", "reconnects", "connected"}}
+	for _, r := range report {
+		rows = append(rows, []string{
+			strconv.FormatUint(uint64(r.UserID), 10),
+			csvSafe(r.DisplayName),
+			strconv.FormatBool(r.Guest),
+			formatExportTime(r.FirstJoin),
+			formatExportTime(r.LastLeave),
+			strconv.FormatInt(r.TotalSeconds, 10),
+			strconv.Itoa(r.Sessions),
+			strconv.Itoa(r.Reconnects),
+			strconv.FormatBool(r.Connected),
+		})
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
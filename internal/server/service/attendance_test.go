@@ -0,0 +1,65 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"GoMeetings/internal/models"
+)
+
+func TestAttendanceReport(t *testing.T) {
+	base := time.Date(2026, 10, 7, 9, 0, 0, 0, time.UTC)
+	at := func(min int) *time.Time {
+		ts := base.Add(time.Duration(min) * time.Minute)
+		return &ts
+	}
+	sessions := []models.ParticipantSession{
+		{Uid: 1, DisplayName: "Ann", JoinedAt: base, LeftAt: at(10)},
+		{Uid: 2, DisplayName: "Ben", JoinedAt: *at(5), LeftAt: at(20)},
+		// Breakout move: the new session starts before the old one is closed.
+		{Uid: 1, DisplayName: "Ann", JoinedAt: *at(10), LeftAt: at(30)},
+		{Uid: 1, DisplayName: "Ann", JoinedAt: *at(40)},
+	}
+	report := attendanceReport(sessions, *at(50), nil)
+	if len(report) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(report))
+	}
+
+	ann := report[0]
+	// Only the session after the 30-40 gap is a reconnect.
+	if ann.UserID != 1 || ann.Sessions != 3 || ann.Reconnects != 1 {
+		t.Fatalf("unexpected counts: %+v", ann)
+	}
+	if ann.TotalSeconds != 40*60 {
+		t.Fatalf("Ann total = %ds, want %ds", ann.TotalSeconds, 40*60)
+	}
+	if !ann.Connected || ann.LastLeave != 0 {
+		t.Fatalf("Ann is still connected: %+v", ann)
+	}
+
+	ben := report[1]
+	if ben.TotalSeconds != 15*60 || ben.LastLeave != at(20).UnixMilli() || ben.Connected {
+		t.Fatalf("unexpected Ben row: %+v", ben)
+	}
+}
+
+func TestAttendanceReportCapsStaleSessions(t *testing.T) {
+	base := time.Date(2026, 10, 7, 9, 0, 0, 0, time.UTC)
+	ended := base.Add(30 * time.Minute)
+	sessions := []models.ParticipantSession{
+		// Never closed: its node crashed before the meeting ended.
+		{Uid: 1, DisplayName: "Ann", JoinedAt: base},
+		// Laptop and phone at the same time.
+		{Uid: 2, DisplayName: "Ben", JoinedAt: base, LeftAt: &ended},
+		{Uid: 2, DisplayName: "Ben", JoinedAt: base.Add(time.Minute), LeftAt: &ended},
+	}
+	report := attendanceReport(sessions, base.Add(24*time.Hour), &ended)
+
+	ann := report[0]
+	if ann.TotalSeconds != 30*60 || ann.Connected || ann.LastLeave != ended.UnixMilli() {
+		t.Fatalf("stale session should end with the meeting: %+v", ann)
+	}
+	if ben := report[1]; ben.Sessions != 2 || ben.Reconnects != 0 {
+		t.Fatalf("a second device is not a reconnect: %+v", ben)
+	}
+}
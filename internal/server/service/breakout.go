@@ -0,0 +1,540 @@
+package service
+
+import (
+	"GoMeetings/internal/helper"
+	"GoMeetings/internal/models"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	maxBreakoutRooms        = 50
+	maxBreakoutCountdown    = 3600
+	breakoutNameMaxLength   = 100
+	breakoutReturnedByTimer = "countdown"
+)
+
+// breakoutTimers holds the pending auto-return of each main room, keyed by
+// room id. A new countdown or an explicit return replaces it.
+var breakoutTimers = struct {
+	mu     sync.Mutex
+	timers map[uint]*time.Timer
+}{timers: make(map[uint]*time.Timer)}
+
+// RoomBreakoutCreate godoc
+// @Summary Create breakout rooms
+// @Description Creates child rooms of a main room, either from names or as count numbered rooms
+// @Tags Breakout
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param identity formData string true "Main room identity"
+// @Param names formData []string false "Breakout names" collectionFormat(multi)
+// @Param count formData integer false "Number of rooms when names is empty"
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/room/breakout/create [post]
+func RoomBreakoutCreate(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	req := BreakoutCreateRequest{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	parent, ok := loadBreakoutParent(c, uc, req.Identity)
+	if !ok {
+		return
+	}
+	existing, err := listBreakouts(parent)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+
+	names := make([]string, 0, len(req.Names))
+	for _, name := range req.Names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if len(name) > breakoutNameMaxLength {
+			c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "breakout name is too long"})
+			return
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		for i := 0; i < req.Count; i++ {
+			names = append(names, fmt.Sprintf("Breakout %d", len(existing)+i+1))
+		}
+	}
+	if len(names) == 0 {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "names or count is required"})
+		return
+	}
+	if len(existing)+len(names) > maxBreakoutRooms {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": fmt.Sprintf("a room can have at most %d breakout rooms", maxBreakoutRooms)})
+		return
+	}
+
+	created := make([]models.RoomBasic, 0, len(names))
+	err = models.DB.Transaction(func(tx *gorm.DB) error {
+		for _, name := range names {
+			joinCode, err := ensureUniqueJoinCode("", 0)
+			if err != nil {
+				return err
+			}
+			child := models.RoomBasic{
+				Identify: helper.GenerateUUID(),
+				Name:     name,
+				BeginAt:  parent.BeginAt,
+				EndAt:    parent.EndAt,
+				CreateID: parent.CreateID,
+				JoinCode: joinCode,
+				ParentID: parent.ID,
+				Status:   models.RoomStatusScheduled,
+			}
+			if err := tx.Create(&child).Error; err != nil {
+				return err
+			}
+			created = append(created, child)
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+
+	items, err := breakoutItems(created)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": items})
+}
+
+// RoomBreakoutList godoc
+// @Summary List breakout rooms
+// @Description Breakout rooms of a main room with their assigned members and connected peers
+// @Tags Breakout
+// @Security BearerAuth
+// @Produce json
+// @Param identity query string true "Main room identity"
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/room/breakout/list [get]
+func RoomBreakoutList(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	parent, _, ok := loadRoomAndMembership(c, uc, c.Query("identity"))
+	if !ok {
+		return
+	}
+	breakouts, err := listBreakouts(parent)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	items, err := breakoutItems(breakouts)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": items})
+}
+
+// RoomBreakoutAssign godoc
+// @Summary Assign a participant to a breakout room
+// @Description Moves the participant's live connection without a join code. Passing the main room as breakout sends them back.
+// @Tags Breakout
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param identity formData string true "Main room identity"
+// @Param breakout formData string true "Breakout room identity"
+// @Param user_id formData integer true "Participant user ID"
+// @Success 200 {object} map[string]string
+// @Router /auth/room/breakout/assign [post]
+func RoomBreakoutAssign(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	req := BreakoutAssignRequest{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	parent, ok := loadBreakoutParent(c, uc, req.Identity)
+	if !ok {
+		return
+	}
+	if err := moveToBreakout(parent, req.Breakout, req.UserID); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "participant assigned"})
+}
+
+// RoomBreakoutAutoAssign godoc
+// @Summary Spread participants over the breakout rooms
+// @Description Shuffles the participants who are not hosts or co-hosts and assigns them round-robin
+// @Tags Breakout
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param identity formData string true "Main room identity"
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/room/breakout/auto-assign [post]
+func RoomBreakoutAutoAssign(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	req := BreakoutAutoAssignRequest{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	parent, ok := loadBreakoutParent(c, uc, req.Identity)
+	if !ok {
+		return
+	}
+	breakouts, err := listBreakouts(parent)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	if len(breakouts) == 0 {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "create breakout rooms first"})
+		return
+	}
+
+	var members []models.RoomUser
+	if err := models.DB.Where("rid = ?", parent.ID).Find(&members).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	uids := make([]uint, 0, len(members))
+	for i := range members {
+		if roleAllows(effectiveRole(parent, &members[i]), permManageBreakouts) {
+			continue
+		}
+		uids = append(uids, members[i].Uid)
+	}
+	rand.Shuffle(len(uids), func(i, j int) { uids[i], uids[j] = uids[j], uids[i] })
+
+	assignments := make(map[string][]uint, len(breakouts))
+	for i, uid := range uids {
+		child := breakouts[i%len(breakouts)]
+		if err := moveToBreakout(parent, child.Identify, uid); err != nil {
+			c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+			return
+		}
+		assignments[child.Identify] = append(assignments[child.Identify], uid)
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": assignments})
+}
+
+// RoomBreakoutCountdown godoc
+// @Summary Broadcast a breakout countdown
+// @Description Sends breakout_countdown to the main room and every breakout; auto_return brings everyone back when it expires
+// @Tags Breakout
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param identity formData string true "Main room identity"
+// @Param seconds formData integer true "Seconds left (1-3600)"
+// @Param message formData string false "Text shown with the countdown"
+// @Param auto_return formData boolean false "Return everyone to the main room at zero"
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/room/breakout/countdown [post]
+func RoomBreakoutCountdown(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	req := BreakoutCountdownRequest{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	if req.Seconds <= 0 || req.Seconds > maxBreakoutCountdown {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": fmt.Sprintf("seconds must be between 1 and %d", maxBreakoutCountdown)})
+		return
+	}
+	parent, ok := loadBreakoutParent(c, uc, req.Identity)
+	if !ok {
+		return
+	}
+
+	duration := time.Duration(req.Seconds) * time.Second
+	value := map[string]interface{}{
+		"seconds":     req.Seconds,
+		"ends_at":     time.Now().Add(duration).UnixMilli(),
+		"message":     strings.TrimSpace(req.Message),
+		"auto_return": req.AutoReturn,
+	}
+	notifyRoomEvent(parent.Identify, "breakout_countdown", value)
+	for _, child := range breakoutIdentities(parent) {
+		notifyRoomEvent(child, "breakout_countdown", value)
+	}
+
+	parentID := parent.ID
+	breakoutTimers.mu.Lock()
+	if timer, ok := breakoutTimers.timers[parentID]; ok {
+		timer.Stop()
+		delete(breakoutTimers.timers, parentID)
+	}
+	if req.AutoReturn {
+		breakoutTimers.timers[parentID] = time.AfterFunc(duration, func() {
+			var room models.RoomBasic
+			if err := models.DB.First(&room, parentID).Error; err != nil {
+				return
+			}
+			if err := returnFromBreakouts(&room, breakoutReturnedByTimer); err != nil {
+				log.Printf("breakout: auto return for room %s: %v", room.Identify, err)
+			}
+		})
+	}
+	breakoutTimers.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": value})
+}
+
+// RoomBreakoutReturn godoc
+// @Summary Return everyone to the main room
+// @Description Clears all breakout assignments and moves every connected participant back
+// @Tags Breakout
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param identity formData string true "Main room identity"
+// @Success 200 {object} map[string]string
+// @Router /auth/room/breakout/return [post]
+func RoomBreakoutReturn(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	req := BreakoutReturnRequest{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	parent, ok := loadBreakoutParent(c, uc, req.Identity)
+	if !ok {
+		return
+	}
+	if err := returnFromBreakouts(parent, "host"); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "everyone returned to the main room"})
+}
+
+// RoomBreakoutVisit godoc
+// @Summary Visit a breakout room
+// @Description Moves the calling host or co-host into a breakout with their main-room role. Passing the main room as breakout returns them.
+// @Tags Breakout
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param identity formData string true "Main room identity"
+// @Param breakout formData string true "Breakout room identity"
+// @Success 200 {object} map[string]string
+// @Router /auth/room/breakout/visit [post]
+func RoomBreakoutVisit(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	req := BreakoutVisitRequest{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	parent, ok := loadBreakoutParent(c, uc, req.Identity)
+	if !ok {
+		return
+	}
+	if err := moveToBreakout(parent, req.Breakout, uc.Id); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "moved", "data": gin.H{"identity": req.Breakout}})
+}
+
+// loadBreakoutParent loads a main room the caller may run breakouts for.
+func loadBreakoutParent(c *gin.Context, uc *helper.UserClaims, identity string) (*models.RoomBasic, bool) {
+	var room models.RoomBasic
+	if err := models.DB.Where("identify = ?", identity).First(&room).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "room not found"})
+		return nil, false
+	}
+	if room.ParentID != 0 {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "breakout rooms cannot have breakout rooms"})
+		return nil, false
+	}
+	if !guestScopeAllows(uc, room.Identify) || !hasRoomPermission(&room, uc.Id, permManageBreakouts) {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "no permission"})
+		return nil, false
+	}
+	return &room, true
+}
+
+// moveToBreakout assigns uid to the breakout (or back to the main room when
+// target is the main room itself) and moves their live connections there.
+// The breakout membership copies the main-room name, guest flag and role.
+func moveToBreakout(parent *models.RoomBasic, target string, uid uint) error {
+	breakouts, err := listBreakouts(parent)
+	if err != nil {
+		return err
+	}
+	var dest *models.RoomBasic
+	if target == parent.Identify {
+		dest = parent
+	}
+	childIDs := make([]uint, 0, len(breakouts))
+	for i := range breakouts {
+		childIDs = append(childIDs, breakouts[i].ID)
+		if breakouts[i].Identify == target {
+			dest = &breakouts[i]
+		}
+	}
+	if dest == nil {
+		return errors.New("breakout room not found")
+	}
+
+	var membership models.RoomUser
+	if err := models.DB.Where("rid = ? AND uid = ?", parent.ID, uid).First(&membership).Error; err != nil {
+		return errors.New("user is not a member of the room")
+	}
+
+	err = models.DB.Transaction(func(tx *gorm.DB) error {
+		if len(childIDs) > 0 {
+			if err := tx.Where("rid IN ? AND uid = ? AND rid <> ?", childIDs, uid, dest.ID).
+				Delete(&models.RoomUser{}).Error; err != nil {
+				return err
+			}
+		}
+		if dest == parent {
+			return nil
+		}
+		return tx.Where("rid = ? AND uid = ?", dest.ID, uid).Assign(models.RoomUser{
+			DisplayName: membership.DisplayName,
+			IsGuest:     membership.IsGuest,
+			Role:        effectiveRole(parent, &membership),
+		}).FirstOrCreate(&models.RoomUser{Rid: dest.ID, Uid: uid}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	from := make([]string, 0, len(breakouts)+1)
+	if dest != parent {
+		from = append(from, parent.Identify)
+		stopScreenShareForUser(parent, uid, "breakout")
+	}
+	for i := range breakouts {
+		if breakouts[i].ID != dest.ID {
+			from = append(from, breakouts[i].Identify)
+			stopScreenShareForUser(&breakouts[i], uid, "breakout")
+		}
+	}
+	wsHub.moveUser(uid, from, dest.Identify)
+	return nil
+}
+
+// returnFromBreakouts ends the breakout session: assignments are cleared and
+// every connected participant moves back to the main room.
+func returnFromBreakouts(parent *models.RoomBasic, reason string) error {
+	breakoutTimers.mu.Lock()
+	if timer, ok := breakoutTimers.timers[parent.ID]; ok {
+		timer.Stop()
+		delete(breakoutTimers.timers, parent.ID)
+	}
+	breakoutTimers.mu.Unlock()
+
+	breakouts, err := listBreakouts(parent)
+	if err != nil {
+		return err
+	}
+	if len(breakouts) == 0 {
+		return nil
+	}
+	childIDs := make([]uint, 0, len(breakouts))
+	childIdentities := make([]string, 0, len(breakouts))
+	for i := range breakouts {
+		childIDs = append(childIDs, breakouts[i].ID)
+		childIdentities = append(childIdentities, breakouts[i].Identify)
+	}
+	var assigned []models.RoomUser
+	if err := models.DB.Where("rid IN ?", childIDs).Find(&assigned).Error; err != nil {
+		return err
+	}
+	if err := models.DB.Where("rid IN ?", childIDs).Delete(&models.RoomUser{}).Error; err != nil {
+		return err
+	}
+
+	byID := make(map[uint]*models.RoomBasic, len(breakouts))
+	for i := range breakouts {
+		byID[breakouts[i].ID] = &breakouts[i]
+	}
+	moved := make(map[uint]bool, len(assigned))
+	for _, m := range assigned {
+		stopScreenShareForUser(byID[m.Rid], m.Uid, "breakout")
+		if !moved[m.Uid] {
+			moved[m.Uid] = true
+			wsHub.moveUser(m.Uid, childIdentities, parent.Identify)
+		}
+	}
+	notifyRoomEvent(parent.Identify, "breakout_closed", map[string]interface{}{"reason": reason})
+	return nil
+}
+
+func listBreakouts(parent *models.RoomBasic) ([]models.RoomBasic, error) {
+	var breakouts []models.RoomBasic
+	err := models.DB.Where("parent_id = ?", parent.ID).Order("id asc").Find(&breakouts).Error
+	return breakouts, err
+}
+
+func breakoutIdentities(parent *models.RoomBasic) []string {
+	var identities []string
+	if err := models.DB.Model(&models.RoomBasic{}).Where("parent_id = ?", parent.ID).
+		Pluck("identify", &identities).Error; err != nil {
+		return nil
+	}
+	return identities
+}
+
+// breakoutParent loads the main room of a breakout.
+func breakoutParent(room *models.RoomBasic) (*models.RoomBasic, error) {
+	var parent models.RoomBasic
+	if err := models.DB.First(&parent, room.ParentID).Error; err != nil {
+		return nil, err
+	}
+	return &parent, nil
+}
+
+// isBreakoutOf reports whether childIdentity is a breakout of parentIdentity.
+func isBreakoutOf(childIdentity, parentIdentity string) bool {
+	if childIdentity == "" || parentIdentity == "" {
+		return false
+	}
+	var count int64
+	models.DB.Model(&models.RoomBasic{}).
+		Where("identify = ? AND parent_id IN (?)", childIdentity,
+			models.DB.Model(&models.RoomBasic{}).Select("id").Where("identify = ?", parentIdentity)).
+		Count(&count)
+	return count > 0
+}
+
+func breakoutItems(breakouts []models.RoomBasic) ([]BreakoutRoomItem, error) {
+	members, err := loadMembersForRooms(breakouts)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]BreakoutRoomItem, 0, len(breakouts))
+	for _, room := range breakouts {
+		items = append(items, BreakoutRoomItem{
+			Identity:  room.Identify,
+			Name:      room.Name,
+			Members:   members[room.ID],
+			Connected: wsHub.roomPeerCount(room.Identify),
+		})
+	}
+	return items, nil
+}
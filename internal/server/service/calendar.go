@@ -0,0 +1,228 @@
+package service
+
+import (
+	"GoMeetings/internal/define"
+	"GoMeetings/internal/helper"
+	"GoMeetings/internal/ical"
+	"GoMeetings/internal/models"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const calendarProdID = "-//GoMeetings//Meetings//EN"
+
+// RoomICS godoc
+// @Summary Download a room as iCalendar
+// @Description VEVENT with the join link, short code and organizer; recurring rooms include RRULE and exceptions
+// @Tags Calendar
+// @Security BearerAuth
+// @Produce text/calendar
+// @Param identity query string true "Room identity"
+// @Success 200 {string} string "text/calendar"
+// @Router /auth/room/ics [get]
+func RoomICS(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	room, _, ok := loadRoomAndMembership(c, uc, c.Query("identity"))
+	if !ok {
+		return
+	}
+	events, err := roomCalendarEvents([]models.RoomBasic{*room})
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	cal := ical.Calendar{ProdID: calendarProdID, Method: "PUBLISH", Events: events}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.ics"`, room.Identify))
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", cal.Marshal())
+}
+
+// UserCalendarToken godoc
+// @Summary Create or rotate the calendar feed URL
+// @Description Returns a secret URL listing every room the user created or joined. Rotating invalidates the previous URL.
+// @Tags Calendar
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/user/calendar-token [post]
+func UserCalendarToken(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	raw, hash, err := newRefreshToken()
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	if err := models.DB.Model(&models.UserBasic{}).Where("id = ?", uc.Id).
+		Update("calendar_token", hash).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": gin.H{
+		"feed_url": publicBaseURL() + "/calendar/" + raw + ".ics",
+	}})
+}
+
+// CalendarFeed godoc
+// @Summary Calendar subscription feed
+// @Description Public feed addressed by the secret from /auth/user/calendar-token
+// @Tags Calendar
+// @Produce text/calendar
+// @Param token path string true "Feed secret followed by .ics"
+// @Success 200 {string} string "text/calendar"
+// @Router /calendar/{token} [get]
+func CalendarFeed(c *gin.Context) {
+	raw := strings.TrimSuffix(c.Param("token"), ".ics")
+	if raw == "" {
+		c.String(http.StatusNotFound, "not found")
+		return
+	}
+	var user models.UserBasic
+	if err := models.DB.Where("calendar_token = ?", hashRefreshToken(raw)).First(&user).Error; err != nil || user.Disabled {
+		c.String(http.StatusNotFound, "not found")
+		return
+	}
+
+	query, joined, err := userRoomsQuery(user.ID, time.Now().Add(-define.CalendarCancelledRetention))
+	if err != nil {
+		c.String(http.StatusInternalServerError, "system error")
+		return
+	}
+	var rooms []models.RoomBasic
+	if err := query.Order("begin_at asc").Find(&rooms).Error; err != nil {
+		c.String(http.StatusInternalServerError, "system error")
+		return
+	}
+	// Live rooms need a live membership; deleted rooms are listed as
+	// cancelled for everyone who still had them.
+	visible := rooms[:0]
+	for _, room := range rooms {
+		_, member := joined[room.ID]
+		if room.CreateID == user.ID || joined[room.ID] || (room.DeletedAt.Valid && member) {
+			visible = append(visible, room)
+		}
+	}
+
+	events, err := roomCalendarEvents(visible)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "system error")
+		return
+	}
+	cal := ical.Calendar{ProdID: calendarProdID, Name: "GoMeetings", Method: "PUBLISH", Events: events}
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", cal.Marshal())
+}
+
+// roomCalendarEvents builds the VEVENTs of the rooms. A recurring room is a
+// master event with EXDATEs for cancelled occurrences plus one event per
+// rescheduled occurrence, all sharing the room's UID and SEQUENCE.
+func roomCalendarEvents(rooms []models.RoomBasic) ([]ical.Event, error) {
+	organizers, err := loadOrganizers(rooms)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	events := make([]ical.Event, 0, len(rooms))
+	for i := range rooms {
+		room := &rooms[i]
+		base := ical.Event{
+			UID:         room.Identify + "@gomeetings",
+			Sequence:    room.Sequence,
+			Stamp:       now,
+			Summary:     room.Name,
+			Description: roomCalendarDescription(room),
+			URL:         roomJoinURL(room),
+			Location:    roomJoinURL(room),
+			Organizer:   organizers[room.CreateID],
+			Status:      ical.StatusConfirmed,
+		}
+		if room.DeletedAt.Valid || room.Status == models.RoomStatusCancelled {
+			base.Status = ical.StatusCancelled
+		}
+
+		master := base
+		master.Start, master.End = room.BeginAt, room.EndAt
+		if room.RRule == "" || base.Status == ical.StatusCancelled {
+			master.RRule = room.RRule
+			events = append(events, master)
+			continue
+		}
+
+		var overrides []models.RoomOccurrence
+		if err := models.DB.Where("rid = ?", room.ID).Order("original_start asc").Find(&overrides).Error; err != nil {
+			return nil, err
+		}
+		master.RRule = room.RRule
+		var moved []ical.Event
+		for _, o := range overrides {
+			if o.Cancelled {
+				master.ExDates = append(master.ExDates, o.OriginalStart)
+				continue
+			}
+			occ := base
+			occ.RecurrenceID = o.OriginalStart
+			occ.Start, occ.End = o.BeginAt, o.EndAt
+			moved = append(moved, occ)
+		}
+		events = append(events, master)
+		events = append(events, moved...)
+	}
+	return events, nil
+}
+
+func loadOrganizers(rooms []models.RoomBasic) (map[uint]*ical.Organizer, error) {
+	ids := make([]uint, 0, len(rooms))
+	for _, room := range rooms {
+		ids = append(ids, room.CreateID)
+	}
+	organizers := make(map[uint]*ical.Organizer, len(ids))
+	if len(ids) == 0 {
+		return organizers, nil
+	}
+	var users []models.UserBasic
+	if err := models.DB.Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		org := &ical.Organizer{Name: u.Username}
+		if strings.Contains(u.Username, "@") {
+			org.Email = u.Username
+		}
+		organizers[u.ID] = org
+	}
+	return organizers, nil
+}
+
+func roomCalendarDescription(room *models.RoomBasic) string {
+	lines := []string{"Join: " + roomJoinURL(room)}
+	if room.ShortCode != "" {
+		lines = append(lines, "Short code: "+room.ShortCode)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// publicBaseURL is the externally reachable origin of the API.
+func publicBaseURL() string {
+	if base := strings.TrimSpace(os.Getenv("PUBLIC_BASE_URL")); base != "" {
+		return strings.TrimRight(base, "/")
+	}
+	return define.DefaultPublicBaseURL
+}
+
+// roomJoinURL points at the room. JOIN_URL_TEMPLATE may route it to a
+// frontend with {short_code} and {identity} placeholders; relative templates
+// are resolved against PUBLIC_BASE_URL.
+func roomJoinURL(room *models.RoomBasic) string {
+	tmpl := os.Getenv("JOIN_URL_TEMPLATE")
+	if tmpl == "" {
+		tmpl = "/auth/room/lookup?short_code={short_code}"
+	}
+	link := strings.NewReplacer("{short_code}", room.ShortCode, "{identity}", room.Identify).Replace(tmpl)
+	if strings.HasPrefix(link, "/") {
+		link = publicBaseURL() + link
+	}
+	return link
+}
@@ -0,0 +1,280 @@
+package service
+
+import (
+	"GoMeetings/internal/helper"
+	"GoMeetings/internal/models"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxChatMessageLength = 4000
+	defaultChatPageSize  = 50
+	maxChatPageSize      = 200
+)
+
+var errChatTooLong = errors.New("message must be 1-4000 characters")
+
+// handleChatSignal persists {"key":"chat_message","value":{"text":"hi","client_id":"c1"}}
+// and delivers it stamped with the stored id. With target_identity (a user
+// or one of their devices) the message is private to the sender and that
+// user, on all of their devices.
+func handleChatSignal(sender *peerConn, msg *signalMessage) {
+	roomIdentity := sender.currentRoom()
+	var value struct {
+		Text     string `json:"text"`
+		ClientID string `json:"client_id"`
+	}
+	if err := json.Unmarshal(msg.Value, &value); err != nil {
+		_ = sender.sendBytes(buildErrorPayload(roomIdentity, errCodeInvalidMessage, "chat_message value must be {\"text\": ...}"))
+		return
+	}
+	text, err := normalizeChatText(value.Text)
+	if err != nil {
+		_ = sender.sendBytes(buildErrorPayload(roomIdentity, errCodeInvalidMessage, err.Error()))
+		return
+	}
+
+	var room models.RoomBasic
+	if err := models.DB.Where("identify = ?", roomIdentity).First(&room).Error; err != nil {
+		_ = sender.sendBytes(buildErrorPayload(roomIdentity, errCodeNotFound, "room not found"))
+		return
+	}
+	chat := models.ChatMessage{
+		Rid:            room.ID,
+		SenderUid:      sender.uid,
+		SenderIdentity: sender.user,
+		SenderName:     chatSenderName(&room, sender),
+		Content:        text,
+	}
+	if msg.TargetIdentity != "" {
+		target, ok := wsHub.member(roomIdentity, msg.TargetIdentity)
+		if !ok {
+			_ = sender.sendBytes(buildErrorPayload(roomIdentity, errCodeNotFound, "recipient is not connected"))
+			return
+		}
+		chat.RecipientUid = target.UID
+		chat.RecipientIdentity = target.User
+	}
+	if err := models.DB.Create(&chat).Error; err != nil {
+		log.Printf("signal: store chat message: %v", err)
+		_ = sender.sendBytes(buildErrorPayload(roomIdentity, errCodeInternal, "message could not be stored"))
+		return
+	}
+
+	item := chatMessageItem(&chat)
+	item.ClientID = value.ClientID
+	out := signalMessage{
+		UserIdentity:   sender.user,
+		RoomIdentity:   roomIdentity,
+		Key:            "chat_message",
+		Value:          mustRawMessage(item),
+		TargetIdentity: chat.RecipientIdentity,
+		Timestamp:      chat.CreatedAt.UnixMilli(),
+	}
+	payload, err := json.Marshal(out)
+	if err != nil {
+		return
+	}
+	deliverChat(room.Identify, &chat, payload)
+}
+
+// deliverChat sends payload to everyone who can see the message.
+func deliverChat(roomIdentity string, chat *models.ChatMessage, payload []byte) {
+	if chat.RecipientUid == 0 {
+		wsHub.broadcast(roomIdentity, payload)
+		return
+	}
+	wsHub.sendToUser(roomIdentity, chat.SenderUid, payload)
+	if chat.RecipientUid != chat.SenderUid {
+		wsHub.sendToUser(roomIdentity, chat.RecipientUid, payload)
+	}
+}
+
+func chatSenderName(room *models.RoomBasic, sender *peerConn) string {
+	var membership models.RoomUser
+	if err := models.DB.Where("rid = ? AND uid = ?", room.ID, sender.uid).First(&membership).Error; err == nil {
+		return membership.DisplayName
+	}
+	return sender.user
+}
+
+func normalizeChatText(text string) (string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" || utf8.RuneCountInString(text) > maxChatMessageLength {
+		return "", errChatTooLong
+	}
+	return text, nil
+}
+
+// RoomChatHistory godoc
+// @Summary Chat history
+// @Description Messages visible to the caller, oldest first. Page backwards with before_id.
+// @Tags Chat
+// @Security BearerAuth
+// @Produce json
+// @Param identity query string true "Room identity"
+// @Param before_id query int false "Only messages older than this id"
+// @Param limit query int false "Page size (max 200)"
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/room/chat/history [get]
+func RoomChatHistory(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	req := ChatHistoryRequest{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	room, _, ok := loadRoomAndMembership(c, uc, req.Identity)
+	if !ok {
+		return
+	}
+	if req.Limit <= 0 {
+		req.Limit = defaultChatPageSize
+	}
+	if req.Limit > maxChatPageSize {
+		req.Limit = maxChatPageSize
+	}
+
+	query := models.DB.Where("rid = ?", room.ID).
+		Where("recipient_uid = 0 OR sender_uid = ? OR recipient_uid = ?", uc.Id, uc.Id)
+	if req.BeforeID > 0 {
+		query = query.Where("id < ?", req.BeforeID)
+	}
+	var messages []models.ChatMessage
+	if err := query.Order("id desc").Limit(req.Limit + 1).Find(&messages).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	hasMore := len(messages) > req.Limit
+	if hasMore {
+		messages = messages[:req.Limit]
+	}
+	list := make([]ChatMessageItem, len(messages))
+	for i := range messages {
+		list[len(messages)-1-i] = chatMessageItem(&messages[i])
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": ChatHistoryReply{List: list, HasMore: hasMore}})
+}
+
+// RoomChatEdit godoc
+// @Summary Edit a chat message
+// @Description Allowed for the author and for hosts/co-hosts
+// @Tags Chat
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param identity formData string true "Room identity"
+// @Param message_id formData integer true "Message ID"
+// @Param text formData string true "New text"
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/room/chat/edit [post]
+func RoomChatEdit(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	req := ChatEditRequest{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	text, err := normalizeChatText(req.Text)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	room, chat, ok := loadManagedChatMessage(c, uc, req.Identity, req.MessageID)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	if err := models.DB.Model(chat).Updates(map[string]interface{}{
+		"content":   text,
+		"edited_at": now,
+	}).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	chat.Content = text
+	chat.EditedAt = &now
+
+	item := chatMessageItem(chat)
+	deliverChat(room.Identify, chat, buildSystemPayload(room.Identify, "chat_message_edited", item))
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": item})
+}
+
+// RoomChatDelete godoc
+// @Summary Delete a chat message
+// @Description Allowed for the author and for hosts/co-hosts
+// @Tags Chat
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param identity formData string true "Room identity"
+// @Param message_id formData integer true "Message ID"
+// @Success 200 {object} map[string]string
+// @Router /auth/room/chat/delete [post]
+func RoomChatDelete(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	req := ChatDeleteRequest{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	room, chat, ok := loadManagedChatMessage(c, uc, req.Identity, req.MessageID)
+	if !ok {
+		return
+	}
+	if err := models.DB.Delete(chat).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	deliverChat(room.Identify, chat, buildSystemPayload(room.Identify, "chat_message_deleted", map[string]interface{}{
+		"id":         chat.ID,
+		"deleted_by": uc.Id,
+	}))
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "message deleted"})
+}
+
+// loadManagedChatMessage loads a message the caller may change: their own,
+// or any message when they can moderate the room.
+func loadManagedChatMessage(c *gin.Context, uc *helper.UserClaims, identity string, messageID uint) (*models.RoomBasic, *models.ChatMessage, bool) {
+	room, _, ok := loadRoomAndMembership(c, uc, identity)
+	if !ok {
+		return nil, nil, false
+	}
+	var chat models.ChatMessage
+	if err := models.DB.Where("id = ? AND rid = ?", messageID, room.ID).First(&chat).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "message not found"})
+		return nil, nil, false
+	}
+	if chat.SenderUid != uc.Id && !hasRoomPermission(room, uc.Id, permModerate) {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "no permission"})
+		return nil, nil, false
+	}
+	return room, &chat, true
+}
+
+func chatMessageItem(m *models.ChatMessage) ChatMessageItem {
+	item := ChatMessageItem{
+		ID:                m.ID,
+		SenderID:          m.SenderUid,
+		SenderIdentity:    m.SenderIdentity,
+		SenderName:        m.SenderName,
+		RecipientID:       m.RecipientUid,
+		RecipientIdentity: m.RecipientIdentity,
+		Private:           m.RecipientUid != 0,
+		Text:              m.Content,
+		CreatedAt:         m.CreatedAt.UnixMilli(),
+	}
+	if m.EditedAt != nil {
+		item.EditedAt = m.EditedAt.UnixMilli()
+	}
+	return item
+}
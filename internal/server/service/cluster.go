@@ -0,0 +1,254 @@
+package service
+
+import (
+	"GoMeetings/internal/broker"
+	"GoMeetings/internal/define"
+	"GoMeetings/internal/helper"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// signalEnvelope is what a hub publishes through the broker. Every node
+// applies it to the connections it holds, so a peer is reached no matter
+// which instance it is connected to.
+type signalEnvelope struct {
+	Op      string          `json:"op"`
+	Room    string          `json:"room,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+	// Key is set for forwarded client messages, which may be rewritten for
+	// recipients on another protocol version.
+	Key string `json:"key,omitempty"`
+	// Delivery filters: Target limits delivery to one identity and Exclude
+	// skips one. UID limits it to the user's connections, or skips them when
+	// ExceptUID is set.
+	Target    string `json:"target,omitempty"`
+	Exclude   string `json:"exclude,omitempty"`
+	UID       uint   `json:"uid,omitempty"`
+	ExceptUID bool   `json:"except_uid,omitempty"`
+	// Close and move parameters.
+	Code   int      `json:"code,omitempty"`
+	Reason string   `json:"reason,omitempty"`
+	From   []string `json:"from,omitempty"`
+}
+
+const (
+	opDeliver    = "deliver"
+	opCloseRoom  = "close_room"
+	opDisconnect = "disconnect"
+	opMove       = "move"
+	opLobbyAdmit = "lobby_admit"
+	opLobbyDeny  = "lobby_deny"
+	// opExpireSession ends the parked session of Target in Room, so the
+	// identity can join on another node.
+	opExpireSession = "expire_session"
+)
+
+// StartSignalBroker picks the signaling broker from SIGNAL_BROKER before the
+// server accepts connections or starts its background workers. "memory" (the default) keeps signaling inside
+// this process; "redis" connects every instance sharing REDIS_URL.
+func StartSignalBroker() error {
+	switch kind := os.Getenv("SIGNAL_BROKER"); kind {
+	case "", "memory":
+		return nil
+	case "redis":
+		url := os.Getenv("REDIS_URL")
+		if url == "" {
+			url = define.DefaultRedisURL
+		}
+		opts, err := redis.ParseURL(url)
+		if err != nil {
+			return fmt.Errorf("REDIS_URL: %w", err)
+		}
+		node, _ := os.Hostname()
+		node += "-" + helper.GenerateUUID()[:8]
+		b, err := broker.NewRedis(redis.NewClient(opts), define.SignalBrokerPrefix, node)
+		if err != nil {
+			return err
+		}
+		hub := newSignalHub(b)
+		hub.runSlowOpsInBackground()
+		if err := hub.subscribe(); err != nil {
+			_ = b.Close()
+			return err
+		}
+		wsHub = hub
+		log.Printf("signal: redis broker connected as node %s", node)
+		return nil
+	default:
+		return fmt.Errorf("unknown SIGNAL_BROKER %q", kind)
+	}
+}
+
+func brokerContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), define.SignalBrokerTimeout)
+}
+
+// subscribe starts applying envelopes published by any node.
+func (h *signalHub) subscribe() error {
+	return h.broker.Subscribe(h.handleEnvelope)
+}
+
+// publish sends env to every node. With the in-memory broker it has been
+// applied when publish returns, so callers must not hold h.mu.
+func (h *signalHub) publish(env signalEnvelope) {
+	raw, err := json.Marshal(env)
+	if err != nil {
+		log.Printf("signal: marshal %s envelope: %v", env.Op, err)
+		return
+	}
+	ctx, cancel := brokerContext()
+	defer cancel()
+	if err := h.broker.Publish(ctx, raw); err != nil {
+		log.Printf("signal: publish %s for room %s: %v", env.Op, env.Room, err)
+	}
+}
+
+func (h *signalHub) handleEnvelope(raw []byte) {
+	var env signalEnvelope
+	if err := json.Unmarshal(raw, &env); err != nil {
+		log.Printf("signal: invalid envelope: %v", err)
+		return
+	}
+	switch env.Op {
+	case opDeliver:
+		h.deliverLocal(&env)
+	case opCloseRoom:
+		h.runSlow(func() { h.closeLocalRoom(env.Room, env.Code, env.Reason) })
+	case opDisconnect:
+		h.runSlow(func() { h.disconnectLocal(env.Room, env.UID, env.Code, env.Reason) })
+	case opMove:
+		h.runSlow(func() { h.moveLocal(env.UID, env.From, env.Room) })
+	case opLobbyAdmit:
+		h.runSlow(func() { h.admitLocal(env.Room, env.UID) })
+	case opLobbyDeny:
+		h.runSlow(func() { h.denyLocal(env.Room, env.UID, env.Reason) })
+	case opExpireSession:
+		h.runSlow(func() { h.expireParked(env.Room, env.Target) })
+	default:
+		log.Printf("signal: unknown envelope op %q", env.Op)
+	}
+}
+
+// slowOpQueue bounds the slow envelope ops waiting for the worker; beyond
+// it the subscriber waits.
+const slowOpQueue = 256
+
+// runSlowOpsInBackground moves slow envelope ops to one worker goroutine.
+// The Redis subscriber applies every envelope of the node on one goroutine,
+// so a close waiting on a stuck connection or a move waiting on a claim
+// would otherwise hold up deliveries to every room. The ops stay in order
+// among themselves, but deliveries published after one may overtake it.
+// Call it before subscribe.
+func (h *signalHub) runSlowOpsInBackground() {
+	h.slowOps = make(chan func(), slowOpQueue)
+	go func() {
+		for op := range h.slowOps {
+			op()
+		}
+	}()
+}
+
+// runSlow runs op on the slow-op worker, or right away without one (the
+// in-memory broker calls the handler on the publisher's goroutine anyway).
+func (h *signalHub) runSlow(op func()) {
+	if h.slowOps == nil {
+		op()
+		return
+	}
+	h.slowOps <- op
+}
+
+// deliverLocal sends the payload to this node's connections in the room that
+// pass the envelope's filters.
+func (h *signalHub) deliverLocal(env *signalEnvelope) {
+	h.mu.RLock()
+	targets := make([]*peerConn, 0, len(h.rooms[env.Room]))
+	for id, peer := range h.rooms[env.Room] {
+		switch {
+		case env.Target != "" && id != env.Target,
+			env.Exclude != "" && id == env.Exclude,
+			env.UID != 0 && (peer.uid == env.UID) == env.ExceptUID:
+			continue
+		}
+		targets = append(targets, peer)
+	}
+	h.mu.RUnlock()
+
+	rendered := make(map[int][]byte)
+	for _, peer := range targets {
+		payload, ok := rendered[peer.protocol]
+		if !ok {
+			payload = env.Payload
+			if env.Key != "" {
+				payload = renderForProtocol(env.Payload, env.Key, peer.protocol)
+			}
+			rendered[peer.protocol] = payload
+		}
+		if err := peer.sendBytes(payload); err != nil {
+			log.Printf("signal: send error to %s: %v", peer.user, err)
+		}
+	}
+}
+
+// claim registers the peer's identity in the room cluster-wide. It is false
+// when the identity is already connected there, on any node.
+func (h *signalHub) claim(roomIdentity string, peer *peerConn) (bool, error) {
+	ctx, cancel := brokerContext()
+	defer cancel()
+	return h.broker.Claim(ctx, roomIdentity, broker.Member{
+		Identity: peer.identity(),
+		User:     peer.user,
+		Device:   peer.device,
+		UID:      peer.uid,
+	})
+}
+
+// release unregisters the peer's identity from the room. The account's
+// raised hand goes down with its last connection there.
+func (h *signalHub) release(roomIdentity string, peer *peerConn) {
+	ctx, cancel := brokerContext()
+	defer cancel()
+	if err := h.broker.Release(ctx, roomIdentity, peer.identity()); err != nil {
+		log.Printf("signal: release %s in %s: %v", peer.identity(), roomIdentity, err)
+	}
+	h.dropHandIfGone(roomIdentity, peer.uid)
+}
+
+// roomMembers lists the room's connections on all nodes.
+func (h *signalHub) roomMembers(roomIdentity string) []broker.Member {
+	ctx, cancel := brokerContext()
+	defer cancel()
+	members, err := h.broker.Members(ctx, roomIdentity)
+	if err != nil {
+		log.Printf("signal: presence of %s: %v", roomIdentity, err)
+	}
+	return members
+}
+
+// otherMembers lists the connections of the room, except one.
+func (h *signalHub) otherMembers(roomIdentity, except string) []broker.Member {
+	members := h.roomMembers(roomIdentity)
+	others := make([]broker.Member, 0, len(members))
+	for _, m := range members {
+		if m.Identity != except {
+			others = append(others, m)
+		}
+	}
+	return others
+}
+
+// member looks up a connection of the room on any node, by its identity or
+// by the identity of the user it belongs to.
+func (h *signalHub) member(roomIdentity, identity string) (broker.Member, bool) {
+	for _, m := range h.roomMembers(roomIdentity) {
+		if m.Identity == identity || m.User == identity {
+			return m, true
+		}
+	}
+	return broker.Member{}, false
+}
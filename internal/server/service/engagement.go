@@ -0,0 +1,125 @@
+package service
+
+import (
+	"GoMeetings/internal/helper"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RoomEngagementExport godoc
+// @Summary Export polls and Q&A
+// @Description Poll results and the Q&A queue of a room as JSON or CSV, for hosts and co-hosts
+// @Tags Poll
+// @Security BearerAuth
+// @Produce json
+// @Produce text/csv
+// @Param identity query string true "Room identity"
+// @Param format query string false "json (default) or csv"
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/room/engagement/export [get]
+func RoomEngagementExport(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	room, _, ok := loadRoomAndMembership(c, uc, c.Query("identity"))
+	if !ok {
+		return
+	}
+	if !hasRoomPermission(room, uc.Id, permManagePolls) {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "no permission"})
+		return
+	}
+	polls, err := roomPollResults(room, 0)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	questions, err := roomQuestionQueue(room, 0)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+
+	switch c.DefaultQuery("format", "json") {
+	case "json":
+		c.JSON(http.StatusOK, gin.H{"code": 200, "data": EngagementExportReply{
+			Identity:  room.Identify,
+			Name:      room.Name,
+			Polls:     polls,
+			Questions: questions,
+		}})
+	case "csv":
+		body, err := engagementCSV(polls, questions)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-engagement.csv"`, room.Identify))
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", body)
+	default:
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "format must be json or csv"})
+	}
+}
+
+// engagementCSV writes one row per poll option and one per question.
+func engagementCSV(polls []PollItem, questions []QuestionItem) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	rows := [][]string{{"type", "id", "text", "option", "votes", "voters", "author", "upvotes", "answered_at", "created_at"}}
+	for _, p := range polls {
+		for _, opt := range p.Options {
+			rows = append(rows, []string{
+				"poll",
+				strconv.FormatUint(uint64(p.ID), 10),
+				csvSafe(p.Question),
+				csvSafe(opt.Text),
+				strconv.Itoa(opt.Votes),
+				csvSafe(strings.Join(opt.Voters, "; ")),
+				"",
+				"",
+				"",
+				formatExportTime(p.CreatedAt),
+			})
+		}
+	}
+	for _, q := range questions {
+		rows = append(rows, []string{
+			"question",
+			strconv.FormatUint(uint64(q.ID), 10),
+			csvSafe(q.Text),
+			"",
+			"",
+			"",
+			csvSafe(q.AskerName),
+			strconv.Itoa(q.Upvotes),
+			formatExportTime(q.AnsweredAt),
+			formatExportTime(q.CreatedAt),
+		})
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// csvSafe keeps user-provided text from being run as a formula when an
+// export is opened in a spreadsheet.
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// formatExportTime renders a millisecond timestamp as RFC 3339; 0 is empty.
+func formatExportTime(ms int64) string {
+	if ms == 0 {
+		return ""
+	}
+	return time.UnixMilli(ms).UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"GoMeetings/internal/define"
+	"GoMeetings/internal/helper"
+	"GoMeetings/internal/models"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const maxDisplayNameLength = 64
+
+// errDisplayNameSlash rejects '/' in display names: they double as signaling
+// identities, where '/' separates the device (see peerIdentity).
+var errDisplayNameSlash = errors.New("display name must not contain '/'")
+
+// GuestJoin godoc
+// @Summary Join a room as guest
+// @Description Join without an account using the room identity or short code plus join code. Returns a room-scoped guest token.
+// @Tags Guest
+// @Accept multipart/form-data
+// @Produce json
+// @Param identity formData string false "Room identity"
+// @Param short_code formData string false "Room short code"
+// @Param join_code formData string true "Join code"
+// @Param display_name formData string true "Display name"
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/guest/join [post]
+func GuestJoin(c *gin.Context) {
+	req := GuestJoinRequest{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	if strings.TrimSpace(req.Identity) == "" && strings.TrimSpace(req.ShortCode) == "" {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "identity or short_code is required"})
+		return
+	}
+	displayName := strings.TrimSpace(req.DisplayName)
+	if displayName == "" || len(displayName) > maxDisplayNameLength {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "display name must be 1-64 characters"})
+		return
+	}
+	if strings.Contains(displayName, "/") {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": errDisplayNameSlash.Error()})
+		return
+	}
+
+	var room *models.RoomBasic
+	var err error
+	if strings.TrimSpace(req.Identity) != "" {
+		room = &models.RoomBasic{}
+		err = models.DB.Where("identify = ? AND parent_id = 0", req.Identity).First(room).Error
+	} else {
+		room, err = findRoomByShortCode(req.ShortCode)
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "room not found"})
+		return
+	}
+	if err := ensureRoomJoinWindow(room, time.Now()); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	if strings.ToUpper(strings.TrimSpace(req.JoinCode)) != room.JoinCode {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "join code incorrect"})
+		return
+	}
+	if room.Locked {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": errRoomLocked.Error()})
+		return
+	}
+
+	// Guests get a throwaway account so membership, screen sharing and
+	// signaling keep working on plain user ids.
+	guest := models.UserBasic{
+		Username: "guest-" + helper.GenerateUUID(),
+		Role:     models.UserRoleGuest,
+	}
+	inLobby := room.Lobby
+	var entry *models.RoomLobby
+	err = models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&guest).Error; err != nil {
+			return err
+		}
+		if inLobby {
+			var err error
+			entry, _, err = requestLobbyAdmission(tx, room, guest.ID, displayName, true)
+			return err
+		}
+		return tx.Create(&models.RoomUser{
+			Rid:         room.ID,
+			Uid:         guest.ID,
+			DisplayName: displayName,
+			IsGuest:     true,
+		}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	if entry != nil {
+		announceLobbyRequest(room, entry)
+	}
+
+	claims := &helper.UserClaims{
+		Id:     guest.ID,
+		Name:   displayName,
+		Family: helper.GenerateUUID(),
+		Guest:  true,
+		Room:   room.Identify,
+	}
+	token, err := helper.GenerateToken(claims, guestTokenTTL(room, time.Now()))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "Generate Token Error" + err.Error()})
+		return
+	}
+
+	reply := GuestJoinReply{
+		Token:        token,
+		ExpiresAt:    claims.ExpiresAt.UnixMilli(),
+		UserID:       guest.ID,
+		DisplayName:  displayName,
+		RoomIdentity: room.Identify,
+	}
+	if inLobby {
+		reply.LobbyStatus = models.LobbyStatusPending
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": reply})
+}
+
+// guestTokenTTL keeps a guest token valid until shortly after the meeting ends.
+func guestTokenTTL(room *models.RoomBasic, now time.Time) time.Duration {
+	endAt := room.EndAt
+	if room.RRule != "" {
+		if occ, ok, err := upcomingRoomOccurrence(room, now); err == nil && ok {
+			endAt = occ.EndAt
+		}
+	}
+	ttl := endAt.Sub(now) + roomEarlyJoinWindow
+	if ttl < accessTokenTTL() {
+		ttl = accessTokenTTL()
+	}
+	if ttl > define.GuestTokenMaxTTL {
+		ttl = define.GuestTokenMaxTTL
+	}
+	return ttl
+}
+
+// guestScopeAllows reports whether the claims may act on the room. Regular
+// user tokens are not room-scoped; guest tokens also cover the breakouts of
+// their room.
+func guestScopeAllows(uc *helper.UserClaims, roomIdentity string) bool {
+	return !uc.Guest || uc.Room == roomIdentity || isBreakoutOf(roomIdentity, uc.Room)
+}
+
+// findRoomByShortCode looks up a main room. Breakouts have no short code and
+// are only entered through breakout assignment.
+func findRoomByShortCode(code string) (*models.RoomBasic, error) {
+	var room models.RoomBasic
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if err := models.DB.Where("short_code = ? AND parent_id = 0", code).First(&room).Error; err != nil {
+		return nil, err
+	}
+	return &room, nil
+}
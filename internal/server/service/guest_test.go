@@ -0,0 +1,15 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestFindRoomByBlankShortCode(t *testing.T) {
+	// Breakouts have an empty short code; a blank one must not find them.
+	if _, err := findRoomByShortCode("  "); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("err = %v, want ErrRecordNotFound", err)
+	}
+}
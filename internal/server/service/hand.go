@@ -0,0 +1,297 @@
+package service
+
+import (
+	"GoMeetings/internal/broker"
+	"GoMeetings/internal/helper"
+	"GoMeetings/internal/models"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// A peer may send reactionBurst reactions per reactionWindow.
+	reactionBurst     = 5
+	reactionWindow    = 3 * time.Second
+	maxReactionLength = 16
+)
+
+type raisedHand struct {
+	UserIdentity string `json:"user_identity"`
+	UserID       uint   `json:"user_id"`
+	RaisedAt     int64  `json:"raised_at"`
+}
+
+// raiseHand appends the peer's account to its room's hand queue, which the
+// broker keeps for all nodes. It returns false when the hand was already up.
+func (h *signalHub) raiseHand(peer *peerConn) ([]raisedHand, bool) {
+	h.mu.RLock()
+	roomIdentity := peer.currentRoom()
+	registered := h.rooms[roomIdentity][peer.identity()] == peer
+	h.mu.RUnlock()
+	if !registered {
+		return nil, false
+	}
+
+	ctx, cancel := brokerContext()
+	defer cancel()
+	raised, err := h.broker.RaiseHand(ctx, roomIdentity, broker.Hand{
+		User:     peer.user,
+		UID:      peer.uid,
+		RaisedAt: time.Now().UnixMilli(),
+	})
+	if err != nil {
+		log.Printf("signal: raise hand of %s in %s: %v", peer.user, roomIdentity, err)
+	}
+	if !raised {
+		return nil, false
+	}
+	return h.raisedHands(roomIdentity), true
+}
+
+// lowerHands takes the hands of the given users down, or every hand of the
+// room when no user is given. A user is named by any identity one of their
+// connections or their hand goes by. It returns the remaining queue and the
+// identities whose hand was lowered.
+func (h *signalHub) lowerHands(roomIdentity string, userIdentities ...string) ([]raisedHand, []string) {
+	var uids []uint
+	if len(userIdentities) > 0 {
+		uids = h.handOwners(roomIdentity, userIdentities)
+		if len(uids) == 0 {
+			return h.raisedHands(roomIdentity), nil
+		}
+	}
+	ctx, cancel := brokerContext()
+	defer cancel()
+	hands, err := h.broker.LowerHands(ctx, roomIdentity, uids...)
+	if err != nil {
+		log.Printf("signal: lower hands in %s: %v", roomIdentity, err)
+	}
+	lowered := make([]string, 0, len(hands))
+	for _, hand := range hands {
+		lowered = append(lowered, hand.User)
+	}
+	return h.raisedHands(roomIdentity), lowered
+}
+
+// handOwners resolves user identities to the accounts whose hands they name.
+func (h *signalHub) handOwners(roomIdentity string, userIdentities []string) []uint {
+	named := make(map[string]bool, len(userIdentities))
+	for _, id := range userIdentities {
+		named[id] = true
+	}
+	seen := make(map[uint]bool)
+	var uids []uint
+	add := func(uid uint) {
+		if uid != 0 && !seen[uid] {
+			seen[uid] = true
+			uids = append(uids, uid)
+		}
+	}
+	for _, m := range h.roomMembers(roomIdentity) {
+		if named[m.Identity] || named[m.User] {
+			add(m.UID)
+		}
+	}
+	for _, hand := range h.raisedHands(roomIdentity) {
+		if named[hand.UserIdentity] {
+			add(hand.UserID)
+		}
+	}
+	return uids
+}
+
+// dropHandIfGone lowers the account's hand once none of its connections is
+// left in the room on any node.
+func (h *signalHub) dropHandIfGone(roomIdentity string, uid uint) {
+	for _, m := range h.roomMembers(roomIdentity) {
+		if m.UID == uid {
+			return
+		}
+	}
+	ctx, cancel := brokerContext()
+	defer cancel()
+	if _, err := h.broker.LowerHands(ctx, roomIdentity, uid); err != nil {
+		log.Printf("signal: drop hand of user %d in %s: %v", uid, roomIdentity, err)
+	}
+}
+
+// raisedHands returns the room's queue in raise order. Hands of users no
+// longer in the room, left behind by a node that died, are skipped.
+func (h *signalHub) raisedHands(roomIdentity string) []raisedHand {
+	ctx, cancel := brokerContext()
+	defer cancel()
+	queue, err := h.broker.Hands(ctx, roomIdentity)
+	if err != nil {
+		log.Printf("signal: raised hands of %s: %v", roomIdentity, err)
+	}
+	present := make(map[uint]bool)
+	for _, m := range h.roomMembers(roomIdentity) {
+		present[m.UID] = true
+	}
+	hands := make([]raisedHand, 0, len(queue))
+	for _, hand := range queue {
+		if present[hand.UID] {
+			hands = append(hands, raisedHand{UserIdentity: hand.User, UserID: hand.UID, RaisedAt: hand.RaisedAt})
+		}
+	}
+	return hands
+}
+
+// allowReaction applies the per-peer reaction rate limit.
+func (p *peerConn) allowReaction(now time.Time) bool {
+	p.reactionMu.Lock()
+	defer p.reactionMu.Unlock()
+	if now.Sub(p.reactionStart) >= reactionWindow {
+		p.reactionStart = now
+		p.reactionCount = 0
+	}
+	if p.reactionCount >= reactionBurst {
+		return false
+	}
+	p.reactionCount++
+	return true
+}
+
+// handleHandSignal handles {"key":"raise_hand"} and {"key":"lower_hand"}.
+// Anyone can lower their own hand; lowering someone else's, with
+// {"value":{"user_identity":"bob"}} or {"value":{"all":true}}, needs moderation rights.
+func handleHandSignal(sender *peerConn, msg *signalMessage) {
+	roomIdentity := sender.currentRoom()
+	if msg.Key == "raise_hand" {
+		hands, changed := wsHub.raiseHand(sender)
+		if changed {
+			notifyRoomEvent(roomIdentity, "hand_raised", map[string]interface{}{
+				"user_identity": sender.user,
+				"user_id":       sender.uid,
+				"raised_hands":  hands,
+			})
+		}
+		return
+	}
+
+	var value struct {
+		UserIdentity string `json:"user_identity"`
+		All          bool   `json:"all"`
+	}
+	if len(msg.Value) > 0 {
+		if err := json.Unmarshal(msg.Value, &value); err != nil {
+			_ = sender.sendBytes(buildErrorPayload(roomIdentity, errCodeInvalidMessage, "lower_hand value must be {\"user_identity\": ...}"))
+			return
+		}
+	}
+	if !value.All && (value.UserIdentity == "" || value.UserIdentity == sender.user) {
+		lowerRoomHands(roomIdentity, sender.uid, sender.user)
+		return
+	}
+
+	var room models.RoomBasic
+	if err := models.DB.Where("identify = ?", roomIdentity).First(&room).Error; err != nil {
+		_ = sender.sendBytes(buildErrorPayload(roomIdentity, errCodeNotFound, "room not found"))
+		return
+	}
+	if !hasRoomPermission(&room, sender.uid, permModerate) {
+		_ = sender.sendBytes(buildErrorPayload(roomIdentity, errCodeForbidden, "no permission"))
+		return
+	}
+	if value.All {
+		lowerRoomHands(roomIdentity, sender.uid)
+		return
+	}
+	lowerRoomHands(roomIdentity, sender.uid, value.UserIdentity)
+}
+
+// lowerRoomHands lowers the hands of the users (all hands when none are
+// given) and broadcasts hand_lowered when anything changed.
+func lowerRoomHands(roomIdentity string, by uint, userIdentities ...string) []string {
+	hands, lowered := wsHub.lowerHands(roomIdentity, userIdentities...)
+	if len(lowered) > 0 {
+		notifyRoomEvent(roomIdentity, "hand_lowered", map[string]interface{}{
+			"user_identities": lowered,
+			"lowered_by":      by,
+			"raised_hands":    hands,
+		})
+	}
+	return lowered
+}
+
+// handleReactionSignal broadcasts {"key":"reaction","value":{"emoji":"👍"}}
+// to the whole room, sender included.
+func handleReactionSignal(sender *peerConn, msg *signalMessage) {
+	roomIdentity := sender.currentRoom()
+	var value struct {
+		Emoji string `json:"emoji"`
+	}
+	if err := json.Unmarshal(msg.Value, &value); err != nil {
+		_ = sender.sendBytes(buildErrorPayload(roomIdentity, errCodeInvalidMessage, "reaction value must be {\"emoji\": ...}"))
+		return
+	}
+	emoji := strings.TrimSpace(value.Emoji)
+	if emoji == "" || utf8.RuneCountInString(emoji) > maxReactionLength {
+		_ = sender.sendBytes(buildErrorPayload(roomIdentity, errCodeInvalidMessage, "emoji must be 1-16 characters"))
+		return
+	}
+	now := time.Now()
+	if !sender.allowReaction(now) {
+		_ = sender.sendBytes(buildErrorPayload(roomIdentity, errCodeRateLimited, "too many reactions, slow down"))
+		return
+	}
+
+	out := signalMessage{
+		UserIdentity: sender.user,
+		RoomIdentity: roomIdentity,
+		Key:          "reaction",
+		Value:        mustRawMessage(map[string]string{"emoji": emoji}),
+		Timestamp:    now.UnixMilli(),
+	}
+	payload, err := json.Marshal(out)
+	if err != nil {
+		log.Printf("signal: marshal reaction error: %v", err)
+		return
+	}
+	wsHub.broadcast(roomIdentity, payload)
+}
+
+// RoomHandLower godoc
+// @Summary Lower raised hands
+// @Description Hosts and co-hosts lower one participant's hand, or every hand when user_identity is empty
+// @Tags Room
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param identity formData string true "Room identity"
+// @Param user_identity formData string false "Signaling identity of the participant"
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/room/hand/lower [post]
+func RoomHandLower(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	req := HandLowerRequest{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	room, _, ok := loadRoomAndMembership(c, uc, req.Identity)
+	if !ok {
+		return
+	}
+	if !hasRoomPermission(room, uc.Id, permModerate) {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "no permission"})
+		return
+	}
+
+	var lowered []string
+	if req.UserIdentity == "" {
+		lowered = lowerRoomHands(room.Identify, uc.Id)
+	} else {
+		lowered = lowerRoomHands(room.Identify, uc.Id, req.UserIdentity)
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": gin.H{
+		"lowered":      lowered,
+		"raised_hands": wsHub.raisedHands(room.Identify),
+	}})
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRaisedHandOrder(t *testing.T) {
+	h := newLocalSignalHub()
+	alice := &peerConn{room: "r1", user: "alice", uid: 1}
+	bob := &peerConn{room: "r1", user: "bob", uid: 2}
+	h.rooms["r1"] = map[string]*peerConn{"alice": alice, "bob": bob}
+	for _, p := range []*peerConn{alice, bob} {
+		if ok, _ := h.claim("r1", p); !ok {
+			t.Fatalf("claim of %s failed", p.user)
+		}
+	}
+
+	if _, ok := h.raiseHand(bob); !ok {
+		t.Fatal("bob's hand should go up")
+	}
+	if _, ok := h.raiseHand(alice); !ok {
+		t.Fatal("alice's hand should go up")
+	}
+	if _, ok := h.raiseHand(bob); ok {
+		t.Fatal("raising twice should be a no-op")
+	}
+	hands := h.raisedHands("r1")
+	if len(hands) != 2 || hands[0].UserIdentity != "bob" || hands[1].UserIdentity != "alice" {
+		t.Fatalf("unexpected order: %+v", hands)
+	}
+
+	h.detachPeerLocked("r1", bob)
+	h.release("r1", bob)
+	hands, lowered := h.lowerHands("r1", "bob")
+	if len(lowered) != 0 || len(hands) != 1 || hands[0].UserIdentity != "alice" {
+		t.Fatalf("leaving should drop the hand: lowered=%v hands=%+v", lowered, hands)
+	}
+	if _, lowered := h.lowerHands("r1"); len(lowered) != 1 {
+		t.Fatalf("lowering all should lower alice, got %v", lowered)
+	}
+}
+
+func TestReactionRateLimit(t *testing.T) {
+	p := &peerConn{}
+	now := time.Now()
+	for i := 0; i < reactionBurst; i++ {
+		if !p.allowReaction(now) {
+			t.Fatalf("reaction %d should pass", i)
+		}
+	}
+	if p.allowReaction(now) {
+		t.Fatal("burst exceeded but reaction allowed")
+	}
+	if !p.allowReaction(now.Add(reactionWindow)) {
+		t.Fatal("new window should allow reactions")
+	}
+}
@@ -0,0 +1,298 @@
+package service
+
+import (
+	"GoMeetings/internal/helper"
+	"GoMeetings/internal/models"
+	"GoMeetings/internal/webhook"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var (
+	errMeetingNotLive   = errors.New("meeting has not been started by the host")
+	errMeetingCancelled = errors.New("meeting was cancelled")
+	errMeetingEnded     = errors.New("meeting has already ended")
+)
+
+// roomStatusTransitions lists the statuses each status may move to. A
+// scheduled room ends without going live when its time passes unstarted.
+var roomStatusTransitions = map[string][]string{
+	models.RoomStatusScheduled: {models.RoomStatusLive, models.RoomStatusCancelled, models.RoomStatusEnded},
+	models.RoomStatusLive:      {models.RoomStatusEnded, models.RoomStatusScheduled},
+}
+
+func roomStatusAllows(from, to string) bool {
+	for _, next := range roomStatusTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// roomStatus returns the lifecycle status that governs the room: breakouts
+// follow their main room.
+func roomStatus(room *models.RoomBasic) string {
+	if room.ParentID != 0 {
+		if parent, err := breakoutParent(room); err == nil {
+			room = parent
+		}
+	}
+	if room.Status == "" {
+		return models.RoomStatusScheduled
+	}
+	return room.Status
+}
+
+// ensureRoomNotClosed rejects rooms that were cancelled or have ended for good.
+func ensureRoomNotClosed(room *models.RoomBasic) error {
+	switch roomStatus(room) {
+	case models.RoomStatusCancelled:
+		return errMeetingCancelled
+	case models.RoomStatusEnded:
+		return errMeetingEnded
+	}
+	return nil
+}
+
+// ensureMeetingLive is required for signaling and screen sharing.
+func ensureMeetingLive(room *models.RoomBasic) error {
+	if err := ensureRoomNotClosed(room); err != nil {
+		return err
+	}
+	if roomStatus(room) != models.RoomStatusLive {
+		return errMeetingNotLive
+	}
+	return nil
+}
+
+// setRoomStatus moves the room from its current status to status. The update
+// only applies while the stored status is still the one read, so concurrent
+// transitions cannot both win.
+func setRoomStatus(tx *gorm.DB, room *models.RoomBasic, status string, extra map[string]interface{}) error {
+	from := roomStatus(room)
+	if !roomStatusAllows(from, status) {
+		return fmt.Errorf("a %s meeting cannot become %s", from, status)
+	}
+	updates := map[string]interface{}{"status": status}
+	for k, v := range extra {
+		updates[k] = v
+	}
+	result := tx.Model(&models.RoomBasic{}).Where("id = ? AND status = ?", room.ID, from).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("room status changed concurrently, reload and retry")
+	}
+	room.Status = status
+	return nil
+}
+
+// finishMeetingStatus is applied when a meeting closes: recurring rooms wait
+// for their next occurrence, everything else has ended.
+func finishMeetingStatus(room *models.RoomBasic, now time.Time) error {
+	if roomStatus(room) != models.RoomStatusLive {
+		return nil
+	}
+	next := models.RoomStatusEnded
+	if room.RRule != "" {
+		ended, err := roomSeriesEnded(room, now)
+		if err != nil {
+			return err
+		}
+		if !ended {
+			next = models.RoomStatusScheduled
+		}
+	}
+	return setRoomStatus(models.DB, room, next, nil)
+}
+
+// expireUnstartedMeetings ends scheduled rooms the host never started once
+// they are over: one-off rooms after their end, series after their last
+// occurrence. The stored status is what RoomList filters on.
+func expireUnstartedMeetings(now time.Time) {
+	var rooms []models.RoomBasic
+	if err := models.DB.Where("parent_id = 0 AND status = ? AND (rrule <> '' OR end_at < ?)", models.RoomStatusScheduled, now).
+		Find(&rooms).Error; err != nil {
+		log.Printf("scheduler: load unstarted rooms: %v", err)
+		return
+	}
+	for i := range rooms {
+		room := &rooms[i]
+		ended, err := roomSeriesEnded(room, now)
+		if err != nil {
+			log.Printf("scheduler: room %s: %v", room.Identify, err)
+			continue
+		}
+		if !ended {
+			continue
+		}
+		if err := setRoomStatus(models.DB, room, models.RoomStatusEnded, nil); err != nil {
+			log.Printf("scheduler: end unstarted room %s: %v", room.Identify, err)
+		}
+	}
+}
+
+// RoomStart godoc
+// @Summary Start the meeting
+// @Description Moves a scheduled room to live; participants can only connect to live meetings
+// @Tags Room
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param identity formData string true "Room identity"
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/room/start [post]
+func RoomStart(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	req := RoomStatusRequest{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	room, ok := loadLifecycleRoom(c, uc, req.Identity, permRunMeeting)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	if err := ensureRoomJoinWindow(room, now); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	if err := setRoomStatus(models.DB, room, models.RoomStatusLive, nil); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+
+	notifyMeetingAndBreakouts(room, "meeting_started", map[string]interface{}{
+		"started_by": uc.Id,
+		"started_at": now.UnixMilli(),
+	})
+	data := roomWebhookData(room)
+	data["started_at"] = now.UnixMilli()
+	emitWebhookEvent(webhook.MeetingStarted, data)
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": gin.H{"identity": room.Identify, "status": room.Status}})
+}
+
+// RoomEnd godoc
+// @Summary End the meeting
+// @Description Ends a live meeting for everyone: screen shares stop and peers are disconnected
+// @Tags Room
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param identity formData string true "Room identity"
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/room/end [post]
+func RoomEnd(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	req := RoomStatusRequest{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	room, ok := loadLifecycleRoom(c, uc, req.Identity, permRunMeeting)
+	if !ok {
+		return
+	}
+	if roomStatus(room) != models.RoomStatusLive {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "only a live meeting can be ended"})
+		return
+	}
+	if err := forceEndRoom(room, "ended_by_host"); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": gin.H{"identity": room.Identify, "status": room.Status}})
+}
+
+// RoomCancel godoc
+// @Summary Cancel the meeting
+// @Description Cancels a scheduled room (the whole series for recurring rooms); calendar feeds show it as cancelled
+// @Tags Room
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param identity formData string true "Room identity"
+// @Param reason formData string false "Reason shown to participants"
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/room/cancel [post]
+func RoomCancel(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	req := RoomCancelRequest{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if len(reason) > 255 {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "reason must be at most 255 characters"})
+		return
+	}
+	room, ok := loadLifecycleRoom(c, uc, req.Identity, permDeleteRoom)
+	if !ok {
+		return
+	}
+	err := setRoomStatus(models.DB, room, models.RoomStatusCancelled, map[string]interface{}{
+		"cancel_reason": reason,
+		"sequence":      gorm.Expr("sequence + 1"),
+	})
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	room.CancelReason = reason
+	room.Sequence++
+
+	value := map[string]interface{}{"reason": reason}
+	notifyRoomEvent(room.Identify, "meeting_cancelled", value)
+	wsHub.closeRoom(room.Identify, closeMeetingEnded, "meeting cancelled")
+	data := roomWebhookData(room)
+	data["reason"] = reason
+	emitWebhookEvent(webhook.MeetingCancelled, data)
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": gin.H{"identity": room.Identify, "status": room.Status}})
+}
+
+func loadLifecycleRoom(c *gin.Context, uc *helper.UserClaims, identity string, perm roomPermission) (*models.RoomBasic, bool) {
+	var room models.RoomBasic
+	if err := models.DB.Where("identify = ?", identity).First(&room).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "room not found"})
+		return nil, false
+	}
+	if room.ParentID != 0 {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "breakout rooms follow their main room"})
+		return nil, false
+	}
+	if !hasRoomPermission(&room, uc.Id, perm) {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "no permission"})
+		return nil, false
+	}
+	return &room, true
+}
+
+// parseRoomStatuses validates a comma-separated status filter.
+func parseRoomStatuses(raw string) ([]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var statuses []string
+	for _, s := range strings.Split(raw, ",") {
+		s = strings.ToLower(strings.TrimSpace(s))
+		switch s {
+		case models.RoomStatusScheduled, models.RoomStatusLive, models.RoomStatusEnded, models.RoomStatusCancelled:
+			statuses = append(statuses, s)
+		case "":
+		default:
+			return nil, fmt.Errorf("unknown status %q", s)
+		}
+	}
+	return statuses, nil
+}
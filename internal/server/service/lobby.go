@@ -0,0 +1,283 @@
+package service
+
+import (
+	"GoMeetings/internal/helper"
+	"GoMeetings/internal/models"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var (
+	errLobbyEntryNotFound = errors.New("no pending lobby request for this user")
+	errLobbyNotAdmissible = errors.New("no pending or denied lobby request for this user")
+	errLobbyUserBanned    = errors.New("this user is banned from the room")
+	errLobbyDenied        = errors.New("the host denied your request to join")
+)
+
+// RoomLobbyList godoc
+// @Summary Pending lobby requests
+// @Description Lists pending requests, or denied ones with status=denied so a mistaken denial can be admitted after all
+// @Tags Lobby
+// @Security BearerAuth
+// @Produce json
+// @Param identity query string true "Room identity"
+// @Param status query string false "pending (default) or denied"
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/room/lobby [get]
+func RoomLobbyList(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	room, ok := loadLobbyRoom(c, uc, c.Query("identity"))
+	if !ok {
+		return
+	}
+
+	status := c.DefaultQuery("status", models.LobbyStatusPending)
+	if status != models.LobbyStatusPending && status != models.LobbyStatusDenied {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "status must be pending or denied"})
+		return
+	}
+	var entries []models.RoomLobby
+	if err := models.DB.Where("rid = ? AND status = ?", room.ID, status).
+		Order("created_at asc").Find(&entries).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	list := make([]LobbyEntry, 0, len(entries))
+	for _, e := range entries {
+		list = append(list, lobbyEntryReply(&e))
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": list})
+}
+
+// RoomLobbyAdmit godoc
+// @Summary Admit a lobby request
+// @Tags Lobby
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param identity formData string true "Room identity"
+// @Param user_id formData integer true "Waiting user ID"
+// @Success 200 {object} map[string]string
+// @Router /auth/room/lobby/admit [post]
+func RoomLobbyAdmit(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	req := LobbyDecisionRequest{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	room, ok := loadLobbyRoom(c, uc, req.Identity)
+	if !ok {
+		return
+	}
+	if err := admitLobbyEntry(room, req.UserID); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "admitted"})
+}
+
+// RoomLobbyDeny godoc
+// @Summary Deny a lobby request
+// @Tags Lobby
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param identity formData string true "Room identity"
+// @Param user_id formData integer true "Waiting user ID"
+// @Param reason formData string false "Reason shown to the user"
+// @Success 200 {object} map[string]string
+// @Router /auth/room/lobby/deny [post]
+func RoomLobbyDeny(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	req := LobbyDecisionRequest{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	room, ok := loadLobbyRoom(c, uc, req.Identity)
+	if !ok {
+		return
+	}
+	if err := denyLobbyEntry(room, req.UserID, req.Reason); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "denied"})
+}
+
+func loadLobbyRoom(c *gin.Context, uc *helper.UserClaims, identity string) (*models.RoomBasic, bool) {
+	if identity == "" {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "identity is required"})
+		return nil, false
+	}
+	var room models.RoomBasic
+	if err := models.DB.Where("identify = ?", identity).First(&room).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "room not found"})
+		return nil, false
+	}
+	if !hasRoomPermission(&room, uc.Id, permManageLobby) {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "no permission"})
+		return nil, false
+	}
+	return &room, true
+}
+
+// requestLobbyAdmission records a pending request. A denial sticks, so a
+// denied user cannot keep knocking; the host can still admit them. It reports whether the request is new;
+// the caller then tells the hosts with announceLobbyRequest once its
+// transaction has committed.
+func requestLobbyAdmission(tx *gorm.DB, room *models.RoomBasic, uid uint, displayName string, guest bool) (*models.RoomLobby, bool, error) {
+	var entry models.RoomLobby
+	err := tx.Where("rid = ? AND uid = ?", room.ID, uid).First(&entry).Error
+	if err == nil && entry.Status == models.LobbyStatusDenied {
+		return nil, false, errLobbyDenied
+	}
+	isNew := err != nil || entry.Status != models.LobbyStatusPending
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		entry = models.RoomLobby{
+			Rid:         room.ID,
+			Uid:         uid,
+			DisplayName: displayName,
+			IsGuest:     guest,
+			Status:      models.LobbyStatusPending,
+		}
+		err = tx.Create(&entry).Error
+	} else if err == nil {
+		entry.DisplayName = displayName
+		entry.Status = models.LobbyStatusPending
+		err = tx.Model(&entry).Updates(map[string]interface{}{
+			"display_name": displayName,
+			"status":       models.LobbyStatusPending,
+		}).Error
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return &entry, isNew, nil
+}
+
+func announceLobbyRequest(room *models.RoomBasic, entry *models.RoomLobby) {
+	notifyLobbyHosts(room, "lobby_request", lobbyEntryReply(entry))
+}
+
+// admitLobbyEntry turns a pending request into membership and moves the
+// user's lobby connection, if any, into the room. A denied request can be
+// admitted too, so a mistaken denial is not final; bans live in RoomBan.
+func admitLobbyEntry(room *models.RoomBasic, uid uint) error {
+	var entry models.RoomLobby
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		// Kicked and banned users' requests are denied as well; a ban holds.
+		var banned int64
+		if err := tx.Model(&models.RoomBan{}).Where("rid = ? AND uid = ?", room.ID, uid).Count(&banned).Error; err != nil {
+			return err
+		}
+		if banned > 0 {
+			return errLobbyUserBanned
+		}
+		if err := tx.Where("rid = ? AND uid = ? AND status IN ?", room.ID, uid,
+			[]string{models.LobbyStatusPending, models.LobbyStatusDenied}).
+			First(&entry).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errLobbyNotAdmissible
+			}
+			return err
+		}
+		if err := tx.Model(&entry).Update("status", models.LobbyStatusAdmitted).Error; err != nil {
+			return err
+		}
+		return tx.Where("rid = ? AND uid = ?", room.ID, uid).FirstOrCreate(&models.RoomUser{
+			Rid:         room.ID,
+			Uid:         uid,
+			DisplayName: entry.DisplayName,
+			IsGuest:     entry.IsGuest,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	entry.Status = models.LobbyStatusAdmitted
+	wsHub.admitFromLobby(room.Identify, uid)
+	notifyLobbyHosts(room, "lobby_updated", lobbyEntryReply(&entry))
+	return nil
+}
+
+func denyLobbyEntry(room *models.RoomBasic, uid uint, reason string) error {
+	var entry models.RoomLobby
+	if err := models.DB.Where("rid = ? AND uid = ? AND status = ?", room.ID, uid, models.LobbyStatusPending).
+		First(&entry).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errLobbyEntryNotFound
+		}
+		return err
+	}
+	if err := models.DB.Model(&entry).Update("status", models.LobbyStatusDenied).Error; err != nil {
+		return err
+	}
+
+	entry.Status = models.LobbyStatusDenied
+	wsHub.denyFromLobby(room.Identify, uid, reason)
+	notifyLobbyHosts(room, "lobby_updated", lobbyEntryReply(&entry))
+	return nil
+}
+
+// notifyLobbyHosts sends the event to everyone allowed to manage the lobby.
+func notifyLobbyHosts(room *models.RoomBasic, key string, value interface{}) {
+	payload := buildSystemPayload(room.Identify, key, value)
+	for _, uid := range usersWithPermission(room, permManageLobby) {
+		wsHub.sendToUser(room.Identify, uid, payload)
+	}
+}
+
+// handleLobbySignal lets a host admit or deny over the signaling channel
+// with {"key":"lobby_admit","value":{"user_id":42}}.
+func handleLobbySignal(sender *peerConn, msg *signalMessage) {
+	roomIdentity := sender.currentRoom()
+	var value struct {
+		UserID uint   `json:"user_id"`
+		Reason string `json:"reason"`
+	}
+	if err := json.Unmarshal(msg.Value, &value); err != nil || value.UserID == 0 {
+		_ = sender.sendBytes(buildErrorPayload(roomIdentity, errCodeInvalidMessage, "user_id is required"))
+		return
+	}
+	var room models.RoomBasic
+	if err := models.DB.Where("identify = ?", roomIdentity).First(&room).Error; err != nil {
+		_ = sender.sendBytes(buildErrorPayload(roomIdentity, errCodeNotFound, "room not found"))
+		return
+	}
+	if !hasRoomPermission(&room, sender.uid, permManageLobby) {
+		_ = sender.sendBytes(buildErrorPayload(roomIdentity, errCodeForbidden, "no permission"))
+		return
+	}
+
+	var err error
+	if msg.Key == "lobby_admit" {
+		err = admitLobbyEntry(&room, value.UserID)
+	} else {
+		err = denyLobbyEntry(&room, value.UserID, value.Reason)
+	}
+	if err != nil {
+		_ = sender.sendBytes(buildErrorPayload(roomIdentity, errCodeRejected, err.Error()))
+	}
+}
+
+func lobbyEntryReply(e *models.RoomLobby) LobbyEntry {
+	return LobbyEntry{
+		UserID:      e.Uid,
+		DisplayName: e.DisplayName,
+		Guest:       e.IsGuest,
+		Status:      e.Status,
+		RequestedAt: e.UpdatedAt.UnixMilli(),
+	}
+}
+
+// lobbyPending reports whether the room routes uid through the lobby.
+func lobbyPending(room *models.RoomBasic, uid uint) bool {
+	return room.Lobby && room.CreateID != uid
+}
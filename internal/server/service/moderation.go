@@ -0,0 +1,297 @@
+package service
+
+import (
+	"GoMeetings/internal/helper"
+	"GoMeetings/internal/models"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var (
+	errRoomLocked = errors.New("room is locked")
+	errRoomBanned = errors.New("you have been banned from this room")
+)
+
+// RoomKick godoc
+// @Summary Remove a participant
+// @Description Deletes the membership and disconnects the user's signaling connections. They can rejoin unless banned.
+// @Tags Moderation
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param identity formData string true "Room identity"
+// @Param user_id formData integer true "Participant user ID"
+// @Param reason formData string false "Reason shown to the user"
+// @Success 200 {object} map[string]string
+// @Router /auth/room/kick [post]
+func RoomKick(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	req := ModerationRequest{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	room, ok := loadModeratedRoom(c, uc, req.Identity, req.UserID)
+	if !ok {
+		return
+	}
+
+	var membership models.RoomUser
+	if err := models.DB.Where("rid = ? AND uid = ?", room.ID, req.UserID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "user is not a member of the room"})
+		return
+	}
+	if err := removeParticipant(room, req.UserID, "kicked", req.Reason); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "user removed"})
+}
+
+// RoomBanUser godoc
+// @Summary Ban a user from the room
+// @Description Removes the user like a kick and blocks them from joining again
+// @Tags Moderation
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param identity formData string true "Room identity"
+// @Param user_id formData integer true "User ID"
+// @Param reason formData string false "Reason shown to the user"
+// @Success 200 {object} map[string]string
+// @Router /auth/room/ban [post]
+func RoomBanUser(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	req := ModerationRequest{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	room, ok := loadModeratedRoom(c, uc, req.Identity, req.UserID)
+	if !ok {
+		return
+	}
+
+	ban := models.RoomBan{Rid: room.ID, Uid: req.UserID}
+	err := models.DB.Where("rid = ? AND uid = ?", room.ID, req.UserID).Assign(models.RoomBan{
+		BannedBy: uc.Id,
+		Reason:   req.Reason,
+	}).FirstOrCreate(&ban).Error
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	if err := removeParticipant(room, req.UserID, "banned", req.Reason); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "user banned"})
+}
+
+// RoomUnbanUser godoc
+// @Summary Lift a ban
+// @Tags Moderation
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param identity formData string true "Room identity"
+// @Param user_id formData integer true "User ID"
+// @Success 200 {object} map[string]string
+// @Router /auth/room/unban [post]
+func RoomUnbanUser(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	req := ModerationRequest{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	room, ok := loadModeratedRoom(c, uc, req.Identity, 0)
+	if !ok {
+		return
+	}
+	if err := models.DB.Unscoped().Where("rid = ? AND uid = ?", room.ID, req.UserID).
+		Delete(&models.RoomBan{}).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "ban lifted"})
+}
+
+// RoomMute godoc
+// @Summary Ask participants to mute
+// @Description Sends a mute_request system message to one participant, or to everyone but the caller when user_id is omitted
+// @Tags Moderation
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param identity formData string true "Room identity"
+// @Param user_id formData integer false "Participant user ID"
+// @Success 200 {object} map[string]string
+// @Router /auth/room/mute [post]
+func RoomMute(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	req := RoomMuteRequest{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	room, ok := loadModeratedRoom(c, uc, req.Identity, req.UserID)
+	if !ok {
+		return
+	}
+
+	payload := buildSystemPayload(room.Identify, "mute_request", map[string]interface{}{
+		"by":  uc.Id,
+		"all": req.UserID == 0,
+	})
+	if req.UserID == 0 {
+		wsHub.sendToAllExcept(room.Identify, uc.Id, payload)
+	} else {
+		wsHub.sendToUser(room.Identify, req.UserID, payload)
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "mute requested"})
+}
+
+// RoomLock godoc
+// @Summary Lock or unlock the room
+// @Description A locked room rejects new joins, guest joins and lobby requests; current members stay and can reconnect
+// @Tags Moderation
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param identity formData string true "Room identity"
+// @Param locked formData boolean true "Lock (true) or unlock (false)"
+// @Success 200 {object} map[string]string
+// @Router /auth/room/lock [post]
+func RoomLock(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	req := RoomLockRequest{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	room, ok := loadModeratedRoom(c, uc, req.Identity, 0)
+	if !ok {
+		return
+	}
+	if room.Locked != req.Locked {
+		if err := models.DB.Model(room).Update("locked", req.Locked).Error; err != nil {
+			c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+			return
+		}
+		notifyRoomEvent(room.Identify, "room_locked", map[string]interface{}{
+			"locked": req.Locked,
+			"by":     uc.Id,
+		})
+	}
+	if req.Locked {
+		c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "room locked"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "room unlocked"})
+}
+
+// loadModeratedRoom loads the room and checks that the caller may moderate
+// target (0 when the action has no single target). The host cannot be
+// targeted and only the host can act on co-hosts.
+func loadModeratedRoom(c *gin.Context, uc *helper.UserClaims, identity string, target uint) (*models.RoomBasic, bool) {
+	var room models.RoomBasic
+	if err := models.DB.Where("identify = ?", identity).First(&room).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "room not found"})
+		return nil, false
+	}
+	actorRole := roomRole(&room, uc.Id)
+	if !roleAllows(actorRole, permModerate) {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "no permission"})
+		return nil, false
+	}
+	if target == 0 {
+		return &room, true
+	}
+	if target == uc.Id {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "cannot moderate yourself"})
+		return nil, false
+	}
+	if target == room.CreateID {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "the host cannot be moderated"})
+		return nil, false
+	}
+	if roomRole(&room, target) == models.RoomRoleCoHost && !roleAllows(actorRole, permAssignCoHost) {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "only the host can moderate co-hosts"})
+		return nil, false
+	}
+	return &room, true
+}
+
+// removeParticipant drops the user's membership and pending lobby request
+// in the room and its breakouts, stops their screen share and closes their
+// signaling connections, wherever in the meeting they sit.
+func removeParticipant(room *models.RoomBasic, uid uint, action, reason string) error {
+	breakouts, err := listBreakouts(room)
+	if err != nil {
+		return err
+	}
+	rids := []uint{room.ID}
+	identities := []string{room.Identify}
+	for _, b := range breakouts {
+		rids = append(rids, b.ID)
+		identities = append(identities, b.Identify)
+	}
+	err = models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("rid IN ? AND uid = ?", rids, uid).Delete(&models.RoomUser{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.RoomLobby{}).
+			Where("rid IN ? AND uid = ? AND status = ?", rids, uid, models.LobbyStatusPending).
+			Update("status", models.LobbyStatusDenied).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	stopScreenShareForUser(room, uid, action)
+	for i := range breakouts {
+		stopScreenShareForUser(&breakouts[i], uid, action)
+	}
+	wsHub.removeFromRooms(identities, uid, action, reason)
+	notifyRoomEvent(room.Identify, "participant_removed", map[string]interface{}{
+		"user_id": uid,
+		"action":  action,
+	})
+	return nil
+}
+
+// removeFromRooms tells the user's connections in the rooms why they are
+// removed and closes them.
+func (h *signalHub) removeFromRooms(roomIdentities []string, uid uint, action, reason string) {
+	value := map[string]interface{}{"action": action}
+	if reason != "" {
+		value["reason"] = reason
+	}
+	for _, roomIdentity := range roomIdentities {
+		h.sendToUser(roomIdentity, uid, buildSystemPayload(roomIdentity, "removed_from_room", value))
+		h.disconnectFromRoom(roomIdentity, uid, closeRemovedByHost, action)
+	}
+}
+
+// checkRoomAdmission rejects new participants of a locked room and banned
+// users. Existing members are let through by the callers before this check.
+func checkRoomAdmission(room *models.RoomBasic, uid uint) error {
+	if uid == room.CreateID {
+		return nil
+	}
+	var count int64
+	if err := models.DB.Model(&models.RoomBan{}).Where("rid = ? AND uid = ?", room.ID, uid).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errRoomBanned
+	}
+	if room.Locked {
+		return errRoomLocked
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRemoveFromRoomsReachesBreakouts(t *testing.T) {
+	h := newLocalSignalHub()
+	host, _, err := h.join("main", "host", "", 1, protocolV2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, _, err := h.join("b1", "bob", "", 2, protocolV2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h.removeFromRooms([]string{"main", "b1"}, 2, "kick", "")
+
+	var msg signalMessage
+	if err := json.Unmarshal((<-bob.send).payload, &msg); err != nil || msg.Key != "removed_from_room" {
+		t.Fatalf("bob got %+v, %v", msg, err)
+	}
+	if out := <-bob.send; !out.close || out.closeCode != closeRemovedByHost {
+		t.Fatalf("bob was not disconnected: %+v", out)
+	}
+	if len(host.send) != 0 {
+		t.Fatalf("the host received %d messages", len(host.send))
+	}
+}
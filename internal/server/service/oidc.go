@@ -0,0 +1,283 @@
+package service
+
+import (
+	"GoMeetings/internal/helper"
+	"GoMeetings/internal/models"
+	"GoMeetings/internal/sso"
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	oidcStateCookie = "gm_oidc"
+	oidcStateMaxAge = 10 * time.Minute
+)
+
+// oidcLoginState is kept in a short-lived HttpOnly cookie between the login
+// redirect and the callback.
+type oidcLoginState struct {
+	State    string `json:"s"`
+	Nonce    string `json:"n"`
+	Verifier string `json:"v"`
+	// Link is the access token of the signed-in user who asked to link the
+	// provider account; it is checked again on the callback.
+	Link string `json:"l,omitempty"`
+}
+
+var errSSOLinkedElsewhere = errors.New("this sign-in is already linked to another account")
+
+var (
+	oidcMu       sync.Mutex
+	oidcProvider *sso.Provider
+)
+
+// getOIDCProvider lazily discovers the provider configured through
+// OIDC_ISSUER, OIDC_CLIENT_ID, OIDC_CLIENT_SECRET, OIDC_REDIRECT_URL and
+// OIDC_SCOPES. A failed discovery is retried on the next request.
+func getOIDCProvider(ctx context.Context) (*sso.Provider, error) {
+	oidcMu.Lock()
+	defer oidcMu.Unlock()
+	if oidcProvider != nil {
+		return oidcProvider, nil
+	}
+	issuer := strings.TrimSpace(os.Getenv("OIDC_ISSUER"))
+	if issuer == "" {
+		return nil, errors.New("single sign-on is not configured")
+	}
+	provider, err := sso.NewProvider(ctx, sso.Config{
+		Issuer:       issuer,
+		ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+		Scopes:       strings.Fields(os.Getenv("OIDC_SCOPES")),
+	})
+	if err != nil {
+		return nil, err
+	}
+	oidcProvider = provider
+	return provider, nil
+}
+
+// OIDCLogin godoc
+// @Summary Start single sign-on
+// @Description Redirects to the OpenID Connect provider (authorization code + PKCE). With the access token of a signed-in user the provider account is linked to that user instead.
+// @Tags Auth
+// @Param token query string false "Access token of the account to link"
+// @Success 302 {string} string "Redirect to identity provider"
+// @Router /auth/oidc/login [get]
+func OIDCLogin(c *gin.Context) {
+	provider, err := getOIDCProvider(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+
+	state := oidcLoginState{
+		State:    helper.GenerateUUID(),
+		Nonce:    helper.GenerateUUID(),
+		Verifier: sso.NewVerifier(),
+	}
+	link := c.Query("token")
+	if link == "" {
+		link = c.GetHeader("Authorization")
+	}
+	if link != "" {
+		claims, err := authenticateToken(link)
+		if err != nil || claims.Guest {
+			c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "invalid token"})
+			return
+		}
+		state.Link = link
+	}
+	raw, err := json.Marshal(state)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, base64.RawURLEncoding.EncodeToString(raw),
+		int(oidcStateMaxAge.Seconds()), "/auth/oidc", "", c.Request.TLS != nil, true)
+	c.Redirect(http.StatusFound, provider.AuthCodeURL(state.State, state.Nonce, state.Verifier))
+}
+
+// OIDCCallback godoc
+// @Summary Single sign-on callback
+// @Description Completes the OpenID Connect login, creating the account on first login, and returns JWTs. A link flow attaches the provider account to the user who started it.
+// @Tags Auth
+// @Produce json
+// @Param code query string true "Authorization code"
+// @Param state query string true "State"
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/oidc/callback [get]
+func OIDCCallback(c *gin.Context) {
+	if errCode := c.Query("error"); errCode != "" {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "sso error: " + errCode})
+		return
+	}
+	provider, err := getOIDCProvider(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+
+	cookie, err := c.Cookie(oidcStateCookie)
+	c.SetCookie(oidcStateCookie, "", -1, "/auth/oidc", "", c.Request.TLS != nil, true)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "sso session expired, please retry"})
+		return
+	}
+	var state oidcLoginState
+	raw, err := base64.RawURLEncoding.DecodeString(cookie)
+	if err != nil || json.Unmarshal(raw, &state) != nil ||
+		subtle.ConstantTimeCompare([]byte(state.State), []byte(c.Query("state"))) != 1 {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "sso state mismatch"})
+		return
+	}
+
+	identity, err := provider.Exchange(c.Request.Context(), c.Query("code"), state.Verifier, state.Nonce)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+
+	if state.Link != "" {
+		claims, err := authenticateToken(state.Link)
+		if err != nil || claims.Guest {
+			c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "sign in again to link your account"})
+			return
+		}
+		if err := linkSSOIdentity(claims.Id, identity); err != nil {
+			c.JSON(http.StatusOK, gin.H{"code": -1, "msg": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "sso account linked"})
+		return
+	}
+
+	user, err := resolveSSOUser(identity)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	if user.Disabled {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "account is disabled"})
+		return
+	}
+
+	pair, err := issueTokenPair(user)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "Generate Token Error" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": tokenPairData(pair, user)})
+}
+
+// resolveSSOUser returns the user linked to the identity. On first login it
+// creates a new password-less account. It never links to an existing
+// account by username: usernames are chosen freely at registration, so a
+// matching email proves nothing about who owns the account. Existing users
+// link through linkSSOIdentity instead.
+func resolveSSOUser(identity *sso.Identity) (*models.UserBasic, error) {
+	var user models.UserBasic
+	var link models.UserIdentity
+	err := models.DB.Where("issuer = ? AND subject = ?", identity.Issuer, identity.Subject).First(&link).Error
+	if err == nil {
+		if err := models.DB.First(&user, link.Uid).Error; err != nil {
+			return nil, err
+		}
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	err = models.DB.Transaction(func(tx *gorm.DB) error {
+		username, err := availableUsername(tx, ssoUsername(identity))
+		if err != nil {
+			return err
+		}
+		// An empty password never verifies, so the account can only
+		// sign in through SSO until a password is set.
+		user = models.UserBasic{Username: username, Role: models.UserRoleUser}
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.UserIdentity{
+			Uid:     user.ID,
+			Issuer:  identity.Issuer,
+			Subject: identity.Subject,
+			Email:   identity.Email,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// linkSSOIdentity attaches the identity to the signed-in user uid, unless
+// another account already uses it to sign in.
+func linkSSOIdentity(uid uint, identity *sso.Identity) error {
+	var link models.UserIdentity
+	err := models.DB.Where("issuer = ? AND subject = ?", identity.Issuer, identity.Subject).First(&link).Error
+	if err == nil {
+		if link.Uid != uid {
+			return errSSOLinkedElsewhere
+		}
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return models.DB.Create(&models.UserIdentity{
+		Uid:     uid,
+		Issuer:  identity.Issuer,
+		Subject: identity.Subject,
+		Email:   identity.Email,
+	}).Error
+}
+
+// ssoUsername picks the username of a new SSO account. Names listed in
+// ADMIN_USERNAMES are skipped, as they are promoted to admin at startup.
+func ssoUsername(identity *sso.Identity) string {
+	for _, candidate := range []string{identity.PreferredUsername, identity.Email} {
+		if candidate = strings.TrimSpace(candidate); len(candidate) >= 3 && !isAdminUsername(candidate) {
+			return candidate
+		}
+	}
+	subject := identity.Subject
+	if len(subject) > 12 {
+		subject = subject[:12]
+	}
+	return "sso-" + subject
+}
+
+func availableUsername(tx *gorm.DB, base string) (string, error) {
+	if len(base) > 90 {
+		base = base[:90]
+	}
+	candidate := base
+	for attempt := 2; attempt < 2+maxCodeCollisionRetries; attempt++ {
+		var count int64
+		if err := tx.Model(&models.UserBasic{}).Where("username = ?", candidate).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s-%d", base, attempt)
+	}
+	return base + "-" + strings.ToLower(generateCode(defaultShortCodeLength)), nil
+}
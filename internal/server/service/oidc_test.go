@@ -0,0 +1,18 @@
+package service
+
+import (
+	"GoMeetings/internal/sso"
+	"testing"
+)
+
+func TestSSOUsernameSkipsAdminNames(t *testing.T) {
+	t.Setenv("ADMIN_USERNAMES", "root, Admin")
+	identity := &sso.Identity{Subject: "abc", PreferredUsername: "admin", Email: "admin@example.com"}
+	if got := ssoUsername(identity); got != "admin@example.com" {
+		t.Fatalf("username = %q", got)
+	}
+	identity.Email = "ROOT"
+	if got := ssoUsername(identity); got != "sso-abc" {
+		t.Fatalf("username = %q", got)
+	}
+}
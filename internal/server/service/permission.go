@@ -0,0 +1,176 @@
+package service
+
+import (
+	"GoMeetings/internal/models"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+type roomPermission int
+
+const (
+	permEditRoom roomPermission = iota
+	permDeleteRoom
+	permAssignCoHost
+	permAssignRoles
+	permManageLobby
+	permShareScreen
+	permStopAnyShare
+	permModerate
+	permManageBreakouts
+	permManagePolls
+	permAnswerQuestions
+	permViewAttendance
+	permRunMeeting
+)
+
+// rolePermissions is the permission set granted to each participant role.
+var rolePermissions = map[string]map[roomPermission]bool{
+	models.RoomRoleHost: {
+		permEditRoom:        true,
+		permDeleteRoom:      true,
+		permAssignCoHost:    true,
+		permAssignRoles:     true,
+		permManageLobby:     true,
+		permShareScreen:     true,
+		permStopAnyShare:    true,
+		permModerate:        true,
+		permManageBreakouts: true,
+		permManagePolls:     true,
+		permAnswerQuestions: true,
+		permViewAttendance:  true,
+		permRunMeeting:      true,
+	},
+	models.RoomRoleCoHost: {
+		permAssignRoles:     true,
+		permManageLobby:     true,
+		permShareScreen:     true,
+		permStopAnyShare:    true,
+		permModerate:        true,
+		permManageBreakouts: true,
+		permManagePolls:     true,
+		permAnswerQuestions: true,
+		permViewAttendance:  true,
+		permRunMeeting:      true,
+	},
+	models.RoomRolePresenter: {
+		permShareScreen: true,
+	},
+	models.RoomRoleAttendee: {},
+}
+
+var errInvalidRole = errors.New("role must be one of cohost, presenter, attendee")
+
+func roleAllows(role string, perm roomPermission) bool {
+	return rolePermissions[role][perm]
+}
+
+// effectiveRole resolves the role of a membership row; the creator is always
+// the host and rows from before roles existed count as attendees.
+func effectiveRole(room *models.RoomBasic, m *models.RoomUser) string {
+	if m == nil {
+		return ""
+	}
+	if m.Uid == room.CreateID {
+		return models.RoomRoleHost
+	}
+	if _, ok := rolePermissions[m.Role]; !ok || m.Role == models.RoomRoleHost {
+		return models.RoomRoleAttendee
+	}
+	return m.Role
+}
+
+// roomRole returns the user's role in the room, or "" when they are not a
+// participant.
+func roomRole(room *models.RoomBasic, uid uint) string {
+	if uid == room.CreateID {
+		return models.RoomRoleHost
+	}
+	var membership models.RoomUser
+	if err := models.DB.Where("rid = ? AND uid = ?", room.ID, uid).First(&membership).Error; err != nil {
+		return ""
+	}
+	return effectiveRole(room, &membership)
+}
+
+func hasRoomPermission(room *models.RoomBasic, uid uint, perm roomPermission) bool {
+	return roleAllows(roomRole(room, uid), perm)
+}
+
+// usersWithPermission lists the participants whose role grants perm.
+func usersWithPermission(room *models.RoomBasic, perm roomPermission) []uint {
+	uids := []uint{room.CreateID}
+	var members []models.RoomUser
+	if err := models.DB.Where("rid = ? AND uid <> ?", room.ID, room.CreateID).Find(&members).Error; err != nil {
+		return uids
+	}
+	for i := range members {
+		if roleAllows(effectiveRole(room, &members[i]), perm) {
+			uids = append(uids, members[i].Uid)
+		}
+	}
+	return uids
+}
+
+// changeRoomRole applies actor's request to give target the role. Only the
+// host may grant or revoke co-host; co-hosts manage presenters and attendees.
+func changeRoomRole(room *models.RoomBasic, actor, target uint, role string) error {
+	if role != models.RoomRoleCoHost && role != models.RoomRolePresenter && role != models.RoomRoleAttendee {
+		return errInvalidRole
+	}
+	if target == room.CreateID {
+		return errors.New("the host role cannot be changed")
+	}
+	actorRole := roomRole(room, actor)
+	if !roleAllows(actorRole, permAssignRoles) {
+		return errors.New("no permission")
+	}
+
+	var membership models.RoomUser
+	if err := models.DB.Where("rid = ? AND uid = ?", room.ID, target).First(&membership).Error; err != nil {
+		return errors.New("user is not a member of the room")
+	}
+	current := effectiveRole(room, &membership)
+	if (role == models.RoomRoleCoHost || current == models.RoomRoleCoHost) && !roleAllows(actorRole, permAssignCoHost) {
+		return errors.New("only the host can change co-hosts")
+	}
+	if current == role {
+		return nil
+	}
+	if err := models.DB.Model(&membership).Update("role", role).Error; err != nil {
+		return err
+	}
+	if !roleAllows(role, permShareScreen) {
+		stopScreenShareForUser(room, target, "role_changed")
+	}
+
+	notifyRoomEvent(room.Identify, "role_changed", map[string]interface{}{
+		"user_id":  target,
+		"role":     role,
+		"previous": current,
+	})
+	return nil
+}
+
+// handleRoleSignal applies {"key":"role_update","value":{"user_id":42,"role":"presenter"}}
+// from a peer, with the same rules as the REST endpoint.
+func handleRoleSignal(sender *peerConn, msg *signalMessage) {
+	roomIdentity := sender.currentRoom()
+	var value struct {
+		UserID uint   `json:"user_id"`
+		Role   string `json:"role"`
+	}
+	if err := json.Unmarshal(msg.Value, &value); err != nil || value.UserID == 0 {
+		_ = sender.sendBytes(buildErrorPayload(roomIdentity, errCodeInvalidMessage, "user_id and role are required"))
+		return
+	}
+	var room models.RoomBasic
+	if err := models.DB.Where("identify = ?", roomIdentity).First(&room).Error; err != nil {
+		_ = sender.sendBytes(buildErrorPayload(roomIdentity, errCodeNotFound, "room not found"))
+		return
+	}
+	if err := changeRoomRole(&room, sender.uid, value.UserID, strings.ToLower(strings.TrimSpace(value.Role))); err != nil {
+		_ = sender.sendBytes(buildErrorPayload(roomIdentity, errCodeRejected, err.Error()))
+	}
+}
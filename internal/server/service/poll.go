@@ -0,0 +1,336 @@
+package service
+
+import (
+	"GoMeetings/internal/helper"
+	"GoMeetings/internal/models"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	maxPollOptions        = 20
+	maxPollQuestionLength = 500
+	maxPollOptionLength   = 200
+)
+
+// RoomPollCreate godoc
+// @Summary Create a poll
+// @Description Hosts and co-hosts put a single or multiple choice question to the room; poll_created is broadcast
+// @Tags Poll
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param identity formData string true "Room identity"
+// @Param question formData string true "Question"
+// @Param options formData []string true "Options (2-20)" collectionFormat(multi)
+// @Param multiple formData boolean false "Allow several options per voter"
+// @Param anonymous formData boolean false "Hide who voted for what"
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/room/poll/create [post]
+func RoomPollCreate(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	req := PollCreateRequest{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	question := strings.TrimSpace(req.Question)
+	if question == "" || utf8.RuneCountInString(question) > maxPollQuestionLength {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "question must be 1-500 characters"})
+		return
+	}
+	options := make([]string, 0, len(req.Options))
+	for _, opt := range req.Options {
+		opt = strings.TrimSpace(opt)
+		if opt == "" {
+			continue
+		}
+		if utf8.RuneCountInString(opt) > maxPollOptionLength {
+			c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "options must be at most 200 characters"})
+			return
+		}
+		options = append(options, opt)
+	}
+	if len(options) < 2 || len(options) > maxPollOptions {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "a poll needs 2-20 options"})
+		return
+	}
+	room, _, ok := loadRoomAndMembership(c, uc, req.Identity)
+	if !ok {
+		return
+	}
+	if !hasRoomPermission(room, uc.Id, permManagePolls) {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "no permission"})
+		return
+	}
+
+	poll := models.Poll{
+		Rid:       room.ID,
+		CreatedBy: uc.Id,
+		Question:  question,
+		Multiple:  req.Multiple,
+		Anonymous: req.Anonymous,
+	}
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&poll).Error; err != nil {
+			return err
+		}
+		for i, text := range options {
+			if err := tx.Create(&models.PollOption{PollID: poll.ID, Position: i, Text: text}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+
+	item, err := pollResults(&poll, 0)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	notifyRoomEvent(room.Identify, "poll_created", item)
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": item})
+}
+
+// RoomPollVote godoc
+// @Summary Vote in a poll
+// @Description Replaces the caller's previous vote; the updated results are broadcast as poll_results
+// @Tags Poll
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param identity formData string true "Room identity"
+// @Param poll_id formData integer true "Poll ID"
+// @Param option_ids formData []integer true "Selected option IDs" collectionFormat(multi)
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/room/poll/vote [post]
+func RoomPollVote(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	req := PollVoteRequest{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	room, membership, ok := loadRoomAndMembership(c, uc, req.Identity)
+	if !ok {
+		return
+	}
+	poll, ok := loadRoomPoll(c, room, req.PollID)
+	if !ok {
+		return
+	}
+	if poll.ClosedAt != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "poll is closed"})
+		return
+	}
+
+	var options []models.PollOption
+	if err := models.DB.Where("poll_id = ?", poll.ID).Find(&options).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	valid := make(map[uint]bool, len(options))
+	for _, opt := range options {
+		valid[opt.ID] = true
+	}
+	selected := make([]uint, 0, len(req.OptionIDs))
+	seen := make(map[uint]bool, len(req.OptionIDs))
+	for _, id := range req.OptionIDs {
+		if !valid[id] {
+			c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "option not found"})
+			return
+		}
+		if !seen[id] {
+			seen[id] = true
+			selected = append(selected, id)
+		}
+	}
+	if len(selected) == 0 {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "select at least one option"})
+		return
+	}
+	if !poll.Multiple && len(selected) > 1 {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "this poll accepts a single option"})
+		return
+	}
+
+	voterName := resolveDisplayName(membership, uc.Name)
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("poll_id = ? AND uid = ?", poll.ID, uc.Id).Delete(&models.PollVote{}).Error; err != nil {
+			return err
+		}
+		for _, id := range selected {
+			vote := models.PollVote{PollID: poll.ID, OptionID: id, Uid: uc.Id, VoterName: voterName}
+			if err := tx.Create(&vote).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+
+	results, err := pollResults(poll, 0)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	notifyRoomEvent(room.Identify, "poll_results", results)
+	results.MyVotes = selected
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": results})
+}
+
+// RoomPollClose godoc
+// @Summary Close a poll
+// @Description Stops voting and broadcasts the final results as poll_closed
+// @Tags Poll
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param identity formData string true "Room identity"
+// @Param poll_id formData integer true "Poll ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/room/poll/close [post]
+func RoomPollClose(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	req := PollCloseRequest{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	room, _, ok := loadRoomAndMembership(c, uc, req.Identity)
+	if !ok {
+		return
+	}
+	if !hasRoomPermission(room, uc.Id, permManagePolls) {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "no permission"})
+		return
+	}
+	poll, ok := loadRoomPoll(c, room, req.PollID)
+	if !ok {
+		return
+	}
+	if poll.ClosedAt == nil {
+		now := time.Now()
+		if err := models.DB.Model(poll).Update("closed_at", now).Error; err != nil {
+			c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+			return
+		}
+		poll.ClosedAt = &now
+	}
+
+	results, err := pollResults(poll, 0)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	notifyRoomEvent(room.Identify, "poll_closed", results)
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": results})
+}
+
+// RoomPollList godoc
+// @Summary List the polls of a room
+// @Description Polls with current results, oldest first; my_votes holds the caller's selection
+// @Tags Poll
+// @Security BearerAuth
+// @Produce json
+// @Param identity query string true "Room identity"
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/room/poll/list [get]
+func RoomPollList(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	room, _, ok := loadRoomAndMembership(c, uc, c.Query("identity"))
+	if !ok {
+		return
+	}
+	list, err := roomPollResults(room, uc.Id)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": list})
+}
+
+func loadRoomPoll(c *gin.Context, room *models.RoomBasic, pollID uint) (*models.Poll, bool) {
+	var poll models.Poll
+	if err := models.DB.Where("id = ? AND rid = ?", pollID, room.ID).First(&poll).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "poll not found"})
+		return nil, false
+	}
+	return &poll, true
+}
+
+func roomPollResults(room *models.RoomBasic, viewer uint) ([]PollItem, error) {
+	var polls []models.Poll
+	if err := models.DB.Where("rid = ?", room.ID).Order("id asc").Find(&polls).Error; err != nil {
+		return nil, err
+	}
+	list := make([]PollItem, 0, len(polls))
+	for i := range polls {
+		item, err := pollResults(&polls[i], viewer)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, item)
+	}
+	return list, nil
+}
+
+// pollResults tallies the poll. Voter names are left out of anonymous polls;
+// a non-zero viewer gets their own selection in MyVotes.
+func pollResults(poll *models.Poll, viewer uint) (PollItem, error) {
+	var options []models.PollOption
+	if err := models.DB.Where("poll_id = ?", poll.ID).Order("position asc").Find(&options).Error; err != nil {
+		return PollItem{}, err
+	}
+	var votes []models.PollVote
+	if err := models.DB.Where("poll_id = ?", poll.ID).Order("id asc").Find(&votes).Error; err != nil {
+		return PollItem{}, err
+	}
+
+	item := PollItem{
+		ID:        poll.ID,
+		Question:  poll.Question,
+		Multiple:  poll.Multiple,
+		Anonymous: poll.Anonymous,
+		Closed:    poll.ClosedAt != nil,
+		CreatedAt: poll.CreatedAt.UnixMilli(),
+		Options:   make([]PollOptionItem, len(options)),
+	}
+	if poll.ClosedAt != nil {
+		item.ClosedAt = poll.ClosedAt.UnixMilli()
+	}
+	index := make(map[uint]int, len(options))
+	for i, opt := range options {
+		index[opt.ID] = i
+		item.Options[i] = PollOptionItem{ID: opt.ID, Text: opt.Text}
+	}
+	voters := make(map[uint]bool)
+	for _, v := range votes {
+		i, ok := index[v.OptionID]
+		if !ok {
+			continue
+		}
+		item.Options[i].Votes++
+		if !poll.Anonymous {
+			item.Options[i].Voters = append(item.Options[i].Voters, v.VoterName)
+		}
+		voters[v.Uid] = true
+		if viewer != 0 && v.Uid == viewer {
+			item.MyVotes = append(item.MyVotes, v.OptionID)
+		}
+	}
+	item.TotalVoters = len(voters)
+	return item, nil
+}
@@ -0,0 +1,384 @@
+package service
+
+import (
+	"GoMeetings/internal/models"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Signaling protocol versions, picked with ?protocol= when connecting.
+// Version 1 is the original format: SDP and ICE candidates travel as JSON
+// strings under offer_sdp, answer_sdp, offer_candidate and answer_candidate.
+// Version 2 uses offer, answer and candidate with object values and adds
+// media_state. Peers on different versions can share a room: forwarded
+// WebRTC messages are rewritten for each recipient.
+const (
+	protocolV1      = 1
+	protocolV2      = 2
+	defaultProtocol = protocolV1
+)
+
+var supportedProtocols = []int{protocolV1, protocolV2}
+
+// Error codes of {"key":"error","value":{"code":...,"message":...}}.
+const (
+	errCodeInvalidMessage = "invalid_message"
+	errCodeUnknownKey     = "unknown_key"
+	errCodeReservedKey    = "reserved_key"
+	errCodeNotFound       = "not_found"
+	errCodeForbidden      = "forbidden"
+	errCodeRejected       = "rejected"
+	errCodeRateLimited    = "rate_limited"
+	errCodeConflict       = "conflict"
+	errCodeUnavailable    = "unavailable"
+	errCodeInternal       = "internal"
+)
+
+type signalError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Key is the client message the error refers to, when there is one.
+	Key string `json:"key,omitempty"`
+}
+
+func (e *signalError) Error() string { return e.Message }
+
+func errAlreadyConnected(identity, roomIdentity string) *signalError {
+	return &signalError{Code: errCodeConflict, Message: fmt.Sprintf("%s already connected in room %s", identity, roomIdentity)}
+}
+
+// Message categories of the catalog.
+const (
+	categoryWebRTC  = "webrtc"
+	categoryMedia   = "media"
+	categoryChat    = "chat"
+	categoryControl = "control"
+)
+
+type targetRule int
+
+const (
+	targetOptional targetRule = iota
+	targetRequired
+	targetForbidden
+)
+
+// messageSpec describes one message a client may send.
+type messageSpec struct {
+	category string
+	target   targetRule
+	validate func(value json.RawMessage) error
+	// authorize, when set, checks the value against the sender's
+	// permissions in the room before the message is handled.
+	authorize func(room *models.RoomBasic, sender *peerConn, value json.RawMessage) bool
+	// handle processes the message on the server; nil forwards it to the
+	// room, or to target_identity.
+	handle func(sender *peerConn, msg *signalMessage)
+}
+
+// sharedMessages are understood by every protocol version.
+var sharedMessages = map[string]messageSpec{
+	"chat_message": {category: categoryChat, validate: validateChatValue, handle: handleChatSignal},
+	"reaction":     {category: categoryChat, target: targetForbidden, validate: validateReactionValue, handle: handleReactionSignal},
+	"raise_hand":   {category: categoryControl, target: targetForbidden, validate: validateEmptyValue, handle: handleHandSignal},
+	"lower_hand":   {category: categoryControl, target: targetForbidden, validate: validateLowerHandValue, handle: handleHandSignal},
+	"lobby_admit":  {category: categoryControl, target: targetForbidden, validate: validateLobbyValue, handle: handleLobbySignal},
+	"lobby_deny":   {category: categoryControl, target: targetForbidden, validate: validateLobbyValue, handle: handleLobbySignal},
+	"role_update":  {category: categoryControl, target: targetForbidden, validate: validateRoleValue, handle: handleRoleSignal},
+}
+
+// protocolMessages is the catalog of client messages per version.
+var protocolMessages = map[int]map[string]messageSpec{
+	protocolV1: withSharedMessages(map[string]messageSpec{
+		"offer_sdp":        {category: categoryWebRTC, validate: validateLegacyValue},
+		"answer_sdp":       {category: categoryWebRTC, validate: validateLegacyValue},
+		"offer_candidate":  {category: categoryWebRTC, validate: validateLegacyValue},
+		"answer_candidate": {category: categoryWebRTC, validate: validateLegacyValue},
+	}),
+	protocolV2: withSharedMessages(map[string]messageSpec{
+		"offer":       {category: categoryWebRTC, target: targetRequired, validate: validateDescription("offer")},
+		"answer":      {category: categoryWebRTC, target: targetRequired, validate: validateDescription("answer")},
+		"candidate":   {category: categoryWebRTC, target: targetRequired, validate: validateCandidateValue},
+		"media_state": {category: categoryMedia, validate: validateMediaStateValue, authorize: authorizeMediaState},
+	}),
+}
+
+// reservedKeys are sent by the server only; clients may not use them.
+var reservedKeys = map[string]bool{
+	"welcome": true, "session": true, "error": true, "peer_list": true, "peer_joined": true, "peer_left": true,
+	"lobby_status": true, "lobby_request": true, "lobby_updated": true,
+	"role_changed": true, "mute_request": true, "removed_from_room": true, "participant_removed": true, "room_locked": true,
+	"screen_share_started": true, "screen_share_stopped": true, "screen_share_refreshed": true,
+	"chat_message_edited": true, "chat_message_deleted": true,
+	"hand_raised": true, "hand_lowered": true,
+	"poll_created": true, "poll_results": true, "poll_closed": true, "question_asked": true, "question_updated": true,
+	"breakout_moved": true, "breakout_countdown": true, "breakout_closed": true,
+	"meeting_started": true, "meeting_ending": true, "meeting_extended": true, "meeting_ended": true, "meeting_cancelled": true,
+	"occurrence_updated": true, "occurrence_cancelled": true,
+}
+
+// WebRTC keys of version 1 and their version 2 names. Version 2 candidates
+// carry no direction, so version 1 peers receive them as offer_candidate.
+var (
+	legacyToV2 = map[string]string{
+		"offer_sdp":        "offer",
+		"answer_sdp":       "answer",
+		"offer_candidate":  "candidate",
+		"answer_candidate": "candidate",
+	}
+	v2ToLegacy = map[string]string{
+		"offer":     "offer_sdp",
+		"answer":    "answer_sdp",
+		"candidate": "offer_candidate",
+	}
+)
+
+func withSharedMessages(specs map[string]messageSpec) map[string]messageSpec {
+	for key, spec := range sharedMessages {
+		specs[key] = spec
+	}
+	return specs
+}
+
+// parseProtocolVersion reads ?protocol=; empty means version 1.
+func parseProtocolVersion(raw string) (int, error) {
+	if raw == "" {
+		return defaultProtocol, nil
+	}
+	version, err := strconv.Atoi(raw)
+	if err == nil {
+		for _, v := range supportedProtocols {
+			if v == version {
+				return version, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unsupported protocol %q, supported versions: %s", raw, joinInts(supportedProtocols))
+}
+
+// checkClientMessage validates msg against the sender's catalog.
+func checkClientMessage(version int, msg *signalMessage) (messageSpec, *signalError) {
+	msg.Key = strings.TrimSpace(msg.Key)
+	if msg.Key == "" {
+		return messageSpec{}, &signalError{Code: errCodeInvalidMessage, Message: "key is required"}
+	}
+	if reservedKeys[msg.Key] {
+		return messageSpec{}, &signalError{Code: errCodeReservedKey, Message: "key is reserved for server messages", Key: msg.Key}
+	}
+	spec, ok := protocolMessages[version][msg.Key]
+	if !ok {
+		return messageSpec{}, &signalError{Code: errCodeUnknownKey, Message: fmt.Sprintf("unknown key in protocol %d", version), Key: msg.Key}
+	}
+	switch {
+	case spec.target == targetRequired && msg.TargetIdentity == "":
+		return spec, &signalError{Code: errCodeInvalidMessage, Message: "target_identity is required", Key: msg.Key}
+	case spec.target == targetForbidden && msg.TargetIdentity != "":
+		return spec, &signalError{Code: errCodeInvalidMessage, Message: "target_identity is not allowed", Key: msg.Key}
+	}
+	if err := spec.validate(msg.Value); err != nil {
+		return spec, &signalError{Code: errCodeInvalidMessage, Message: err.Error(), Key: msg.Key}
+	}
+	return spec, nil
+}
+
+// renderForProtocol rewrites a forwarded WebRTC message for a recipient on
+// another protocol version. Other payloads are returned unchanged.
+func renderForProtocol(payload []byte, key string, version int) []byte {
+	var renamed string
+	switch version {
+	case protocolV1:
+		renamed = v2ToLegacy[key]
+	case protocolV2:
+		renamed = legacyToV2[key]
+	}
+	if renamed == "" {
+		return payload
+	}
+
+	var msg signalMessage
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		return payload
+	}
+	msg.Key = renamed
+	if version == protocolV1 {
+		// Version 1 clients expect the object encoded as a string.
+		msg.Value = mustRawMessage(string(msg.Value))
+	} else {
+		var inner string
+		if err := json.Unmarshal(msg.Value, &inner); err == nil && json.Valid([]byte(inner)) {
+			msg.Value = json.RawMessage(inner)
+		}
+	}
+	out, err := json.Marshal(msg)
+	if err != nil {
+		return payload
+	}
+	return out
+}
+
+// decodeStrict decodes a JSON object value, rejecting unknown fields.
+func decodeStrict(value json.RawMessage, v interface{}) error {
+	if len(value) == 0 || bytes.Equal(value, []byte("null")) {
+		return errors.New("value is required")
+	}
+	dec := json.NewDecoder(bytes.NewReader(value))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid value: %v", err)
+	}
+	return nil
+}
+
+func isEmptyValue(value json.RawMessage) bool {
+	v := bytes.TrimSpace(value)
+	return len(v) == 0 || bytes.Equal(v, []byte("null")) || bytes.Equal(v, []byte("{}"))
+}
+
+func validateEmptyValue(value json.RawMessage) error {
+	if !isEmptyValue(value) {
+		return errors.New("value must be empty")
+	}
+	return nil
+}
+
+// validateLegacyValue accepts what version 1 clients send: a JSON object or
+// a string holding one.
+func validateLegacyValue(value json.RawMessage) error {
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		value = json.RawMessage(s)
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal(value, &obj); err != nil || len(obj) == 0 {
+		return errors.New("value must be a JSON object or a string holding one")
+	}
+	return nil
+}
+
+func validateDescription(kind string) func(json.RawMessage) error {
+	return func(value json.RawMessage) error {
+		var v struct {
+			Type string `json:"type"`
+			SDP  string `json:"sdp"`
+		}
+		if err := decodeStrict(value, &v); err != nil {
+			return err
+		}
+		if strings.TrimSpace(v.SDP) == "" {
+			return errors.New("sdp is required")
+		}
+		if v.Type != "" && v.Type != kind {
+			return fmt.Errorf("type must be %q", kind)
+		}
+		return nil
+	}
+}
+
+func validateCandidateValue(value json.RawMessage) error {
+	var v struct {
+		Candidate        *string `json:"candidate"`
+		SDPMid           *string `json:"sdpMid"`
+		SDPMLineIndex    *int    `json:"sdpMLineIndex"`
+		UsernameFragment *string `json:"usernameFragment"`
+	}
+	if err := decodeStrict(value, &v); err != nil {
+		return err
+	}
+	if v.Candidate == nil {
+		return errors.New("candidate is required")
+	}
+	if v.SDPMid == nil && v.SDPMLineIndex == nil {
+		return errors.New("sdpMid or sdpMLineIndex is required")
+	}
+	return nil
+}
+
+func validateMediaStateValue(value json.RawMessage) error {
+	var v struct {
+		Audio  *bool `json:"audio"`
+		Video  *bool `json:"video"`
+		Screen *bool `json:"screen"`
+	}
+	if err := decodeStrict(value, &v); err != nil {
+		return err
+	}
+	if v.Audio == nil && v.Video == nil && v.Screen == nil {
+		return errors.New("one of audio, video or screen is required")
+	}
+	return nil
+}
+
+// authorizeMediaState only lets roles that may share announce a screen.
+func authorizeMediaState(room *models.RoomBasic, sender *peerConn, value json.RawMessage) bool {
+	var v struct {
+		Screen bool `json:"screen"`
+	}
+	_ = json.Unmarshal(value, &v)
+	return !v.Screen || hasRoomPermission(room, sender.uid, permShareScreen)
+}
+
+func validateChatValue(value json.RawMessage) error {
+	var v struct {
+		Text     string `json:"text"`
+		ClientID string `json:"client_id"`
+	}
+	return decodeStrict(value, &v)
+}
+
+func validateReactionValue(value json.RawMessage) error {
+	var v struct {
+		Emoji string `json:"emoji"`
+	}
+	return decodeStrict(value, &v)
+}
+
+func validateLowerHandValue(value json.RawMessage) error {
+	if isEmptyValue(value) {
+		return nil
+	}
+	var v struct {
+		UserIdentity string `json:"user_identity"`
+		All          bool   `json:"all"`
+	}
+	return decodeStrict(value, &v)
+}
+
+func validateLobbyValue(value json.RawMessage) error {
+	var v struct {
+		UserID uint   `json:"user_id"`
+		Reason string `json:"reason"`
+	}
+	if err := decodeStrict(value, &v); err != nil {
+		return err
+	}
+	if v.UserID == 0 {
+		return errors.New("user_id is required")
+	}
+	return nil
+}
+
+func validateRoleValue(value json.RawMessage) error {
+	var v struct {
+		UserID uint   `json:"user_id"`
+		Role   string `json:"role"`
+	}
+	if err := decodeStrict(value, &v); err != nil {
+		return err
+	}
+	if v.UserID == 0 || v.Role == "" {
+		return errors.New("user_id and role are required")
+	}
+	return nil
+}
+
+func joinInts(values []int) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, ", ")
+}
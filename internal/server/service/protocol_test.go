@@ -0,0 +1,58 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCheckClientMessage(t *testing.T) {
+	cases := []struct {
+		name    string
+		version int
+		msg     signalMessage
+		code    string
+	}{
+		{"reserved key", protocolV2, signalMessage{Key: "peer_left"}, errCodeReservedKey},
+		{"spoofed error", protocolV1, signalMessage{Key: "error", Value: json.RawMessage(`{}`)}, errCodeReservedKey},
+		{"unknown key", protocolV2, signalMessage{Key: "whatever"}, errCodeUnknownKey},
+		{"v2 key on v1", protocolV1, signalMessage{Key: "offer", TargetIdentity: "bob"}, errCodeUnknownKey},
+		{"missing target", protocolV2, signalMessage{Key: "offer", Value: json.RawMessage(`{"type":"offer","sdp":"v=0"}`)}, errCodeInvalidMessage},
+		{"unknown field", protocolV2, signalMessage{Key: "media_state", Value: json.RawMessage(`{"audio":true,"x":1}`)}, errCodeInvalidMessage},
+		{"empty candidate", protocolV2, signalMessage{Key: "candidate", TargetIdentity: "bob", Value: json.RawMessage(`{}`)}, errCodeInvalidMessage},
+		{"valid offer", protocolV2, signalMessage{Key: "offer", TargetIdentity: "bob", Value: json.RawMessage(`{"type":"offer","sdp":"v=0"}`)}, ""},
+		{"valid legacy offer", protocolV1, signalMessage{Key: "offer_sdp", Value: json.RawMessage(`"{\"type\":\"offer\",\"sdp\":\"v=0\"}"`)}, ""},
+	}
+	for _, tc := range cases {
+		_, err := checkClientMessage(tc.version, &tc.msg)
+		switch {
+		case tc.code == "" && err != nil:
+			t.Errorf("%s: unexpected error %+v", tc.name, err)
+		case tc.code != "" && (err == nil || err.Code != tc.code):
+			t.Errorf("%s: error = %+v, want code %s", tc.name, err, tc.code)
+		}
+	}
+}
+
+func TestRenderForProtocol(t *testing.T) {
+	v2 := []byte(`{"user_identity":"alice","key":"candidate","value":{"candidate":"c","sdpMid":"0"}}`)
+	var msg signalMessage
+	if err := json.Unmarshal(renderForProtocol(v2, "candidate", protocolV1), &msg); err != nil {
+		t.Fatal(err)
+	}
+	var inner string
+	if msg.Key != "offer_candidate" || json.Unmarshal(msg.Value, &inner) != nil || inner != `{"candidate":"c","sdpMid":"0"}` {
+		t.Fatalf("v1 rendering = %s %s", msg.Key, msg.Value)
+	}
+
+	v1 := []byte(`{"user_identity":"alice","key":"answer_sdp","value":"{\"type\":\"answer\",\"sdp\":\"v=0\"}"}`)
+	if err := json.Unmarshal(renderForProtocol(v1, "answer_sdp", protocolV2), &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Key != "answer" || string(msg.Value) != `{"type":"answer","sdp":"v=0"}` {
+		t.Fatalf("v2 rendering = %s %s", msg.Key, msg.Value)
+	}
+
+	if out := renderForProtocol(v1, "answer_sdp", protocolV1); string(out) != string(v1) {
+		t.Fatalf("same-version payload was rewritten: %s", out)
+	}
+}
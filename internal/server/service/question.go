@@ -0,0 +1,248 @@
+package service
+
+import (
+	"GoMeetings/internal/helper"
+	"GoMeetings/internal/models"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const maxQuestionLength = 1000
+
+var errAlreadyUpvoted = errors.New("question already upvoted")
+
+// RoomQuestionAsk godoc
+// @Summary Ask a question
+// @Description Adds a question to the room's Q&A queue and broadcasts question_asked
+// @Tags Q&A
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param identity formData string true "Room identity"
+// @Param text formData string true "Question text"
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/room/question/ask [post]
+func RoomQuestionAsk(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	req := QuestionAskRequest{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	text := strings.TrimSpace(req.Text)
+	if text == "" || utf8.RuneCountInString(text) > maxQuestionLength {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "question must be 1-1000 characters"})
+		return
+	}
+	room, membership, ok := loadRoomAndMembership(c, uc, req.Identity)
+	if !ok {
+		return
+	}
+
+	question := models.RoomQuestion{
+		Rid:       room.ID,
+		AskerUid:  uc.Id,
+		AskerName: resolveDisplayName(membership, uc.Name),
+		Content:   text,
+	}
+	if err := models.DB.Create(&question).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	item := questionItem(&question, false)
+	notifyRoomEvent(room.Identify, "question_asked", item)
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": item})
+}
+
+// RoomQuestionUpvote godoc
+// @Summary Upvote a question
+// @Description Each participant can upvote a question once; question_updated is broadcast
+// @Tags Q&A
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param identity formData string true "Room identity"
+// @Param question_id formData integer true "Question ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/room/question/upvote [post]
+func RoomQuestionUpvote(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	req := QuestionActionRequest{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	room, _, ok := loadRoomAndMembership(c, uc, req.Identity)
+	if !ok {
+		return
+	}
+	question, ok := loadRoomQuestion(c, room, req.QuestionID)
+	if !ok {
+		return
+	}
+	if question.AskerUid == uc.Id {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "you cannot upvote your own question"})
+		return
+	}
+
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.QuestionUpvote{}).Where("question_id = ? AND uid = ?", question.ID, uc.Id).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return errAlreadyUpvoted
+		}
+		if err := tx.Create(&models.QuestionUpvote{QuestionID: question.ID, Uid: uc.Id}).Error; err != nil {
+			return err
+		}
+		return tx.Model(question).Update("upvotes", gorm.Expr("upvotes + 1")).Error
+	})
+	if errors.Is(err, errAlreadyUpvoted) {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	question.Upvotes++
+
+	notifyRoomEvent(room.Identify, "question_updated", questionItem(question, false))
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": questionItem(question, true)})
+}
+
+// RoomQuestionAnswer godoc
+// @Summary Mark a question answered
+// @Description Hosts and co-hosts close a question; question_updated is broadcast
+// @Tags Q&A
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param identity formData string true "Room identity"
+// @Param question_id formData integer true "Question ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/room/question/answer [post]
+func RoomQuestionAnswer(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	req := QuestionActionRequest{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	room, _, ok := loadRoomAndMembership(c, uc, req.Identity)
+	if !ok {
+		return
+	}
+	if !hasRoomPermission(room, uc.Id, permAnswerQuestions) {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "no permission"})
+		return
+	}
+	question, ok := loadRoomQuestion(c, room, req.QuestionID)
+	if !ok {
+		return
+	}
+	if question.AnsweredAt == nil {
+		now := time.Now()
+		if err := models.DB.Model(question).Updates(map[string]interface{}{
+			"answered_at": now,
+			"answered_by": uc.Id,
+		}).Error; err != nil {
+			c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+			return
+		}
+		question.AnsweredAt = &now
+		question.AnsweredBy = uc.Id
+	}
+
+	item := questionItem(question, false)
+	notifyRoomEvent(room.Identify, "question_updated", item)
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": item})
+}
+
+// RoomQuestionList godoc
+// @Summary Q&A queue
+// @Description Open questions first, most upvoted first; answered questions follow
+// @Tags Q&A
+// @Security BearerAuth
+// @Produce json
+// @Param identity query string true "Room identity"
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/room/question/list [get]
+func RoomQuestionList(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	room, _, ok := loadRoomAndMembership(c, uc, c.Query("identity"))
+	if !ok {
+		return
+	}
+	list, err := roomQuestionQueue(room, uc.Id)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": list})
+}
+
+func loadRoomQuestion(c *gin.Context, room *models.RoomBasic, questionID uint) (*models.RoomQuestion, bool) {
+	var question models.RoomQuestion
+	if err := models.DB.Where("id = ? AND rid = ?", questionID, room.ID).First(&question).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "question not found"})
+		return nil, false
+	}
+	return &question, true
+}
+
+// roomQuestionQueue lists the room's questions in queue order. Upvoted is
+// set for the questions viewer upvoted.
+func roomQuestionQueue(room *models.RoomBasic, viewer uint) ([]QuestionItem, error) {
+	var questions []models.RoomQuestion
+	if err := models.DB.Where("rid = ?", room.ID).
+		Order("answered_at IS NOT NULL, upvotes desc, id asc").
+		Find(&questions).Error; err != nil {
+		return nil, err
+	}
+	upvoted := make(map[uint]bool)
+	if viewer != 0 && len(questions) > 0 {
+		ids := make([]uint, 0, len(questions))
+		for _, q := range questions {
+			ids = append(ids, q.ID)
+		}
+		var mine []uint
+		if err := models.DB.Model(&models.QuestionUpvote{}).Where("uid = ? AND question_id IN ?", viewer, ids).
+			Pluck("question_id", &mine).Error; err != nil {
+			return nil, err
+		}
+		for _, id := range mine {
+			upvoted[id] = true
+		}
+	}
+	list := make([]QuestionItem, 0, len(questions))
+	for i := range questions {
+		list = append(list, questionItem(&questions[i], upvoted[questions[i].ID]))
+	}
+	return list, nil
+}
+
+func questionItem(q *models.RoomQuestion, upvoted bool) QuestionItem {
+	item := QuestionItem{
+		ID:        q.ID,
+		AskerID:   q.AskerUid,
+		AskerName: q.AskerName,
+		Text:      q.Content,
+		Upvotes:   q.Upvotes,
+		Upvoted:   upvoted,
+		Answered:  q.AnsweredAt != nil,
+		CreatedAt: q.CreatedAt.UnixMilli(),
+	}
+	if q.AnsweredAt != nil {
+		item.AnsweredAt = q.AnsweredAt.UnixMilli()
+	}
+	return item
+}
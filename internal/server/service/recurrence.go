@@ -0,0 +1,296 @@
+package service
+
+import (
+	"GoMeetings/internal/helper"
+	"GoMeetings/internal/models"
+	"GoMeetings/internal/recurrence"
+	"GoMeetings/internal/webhook"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxOccurrenceRange bounds from/to windows that expand recurring rooms.
+const maxOccurrenceRange = 366 * 24 * time.Hour
+
+var errNotAnOccurrence = errors.New("occurrence is not part of the series")
+
+// roomOccurrence is one concrete meeting window of a room. Single rooms have
+// exactly one, keyed by their begin time.
+type roomOccurrence struct {
+	OriginalStart time.Time
+	BeginAt       time.Time
+	EndAt         time.Time
+	Overridden    bool
+	Cancelled     bool
+}
+
+// RoomOccurrenceEdit godoc
+// @Summary Reschedule one occurrence
+// @Description Moves a single occurrence of a recurring room; the rest of the series is unchanged
+// @Tags Room
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param identity formData string true "Room identity"
+// @Param occurrence formData integer true "Occurrence id (original start, ms)"
+// @Param begin_at formData integer true "New begin time (ms)"
+// @Param end_at formData integer true "New end time (ms)"
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/room/occurrence/edit [put]
+func RoomOccurrenceEdit(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	req := RoomOccurrenceEditRequest{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	if req.EndAt <= req.BeginAt {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "end time must be greater than begin time"})
+		return
+	}
+	room, occ, ok := loadRoomOccurrence(c, uc, req.Identity, req.Occurrence)
+	if !ok {
+		return
+	}
+
+	occ.BeginAt = time.UnixMilli(req.BeginAt)
+	occ.EndAt = time.UnixMilli(req.EndAt)
+	occ.Cancelled = false
+	if err := saveOccurrenceOverride(room, occ); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	item := occurrenceItem(room, occ)
+	notifyRoomEvent(room.Identify, "occurrence_updated", item)
+	emitOccurrenceWebhook(room, item)
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": item})
+}
+
+// RoomOccurrenceCancel godoc
+// @Summary Cancel one occurrence
+// @Description Adds an exception to the series of a recurring room
+// @Tags Room
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param identity formData string true "Room identity"
+// @Param occurrence formData integer true "Occurrence id (original start, ms)"
+// @Success 200 {object} map[string]string
+// @Router /auth/room/occurrence/cancel [post]
+func RoomOccurrenceCancel(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	req := RoomOccurrenceCancelRequest{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	room, occ, ok := loadRoomOccurrence(c, uc, req.Identity, req.Occurrence)
+	if !ok {
+		return
+	}
+
+	occ.Cancelled = true
+	if err := saveOccurrenceOverride(room, occ); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	item := occurrenceItem(room, occ)
+	notifyRoomEvent(room.Identify, "occurrence_cancelled", item)
+	emitOccurrenceWebhook(room, item)
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "occurrence cancelled"})
+}
+
+func emitOccurrenceWebhook(room *models.RoomBasic, item RoomOccurrenceItem) {
+	data := roomWebhookData(room)
+	data["occurrence"] = item
+	emitWebhookEvent(webhook.RoomUpdated, data)
+}
+
+func loadRoomOccurrence(c *gin.Context, uc *helper.UserClaims, identity string, occurrenceID int64) (*models.RoomBasic, roomOccurrence, bool) {
+	var room models.RoomBasic
+	if err := models.DB.Where("identify = ?", identity).First(&room).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "room not found"})
+		return nil, roomOccurrence{}, false
+	}
+	if !hasRoomPermission(&room, uc.Id, permEditRoom) {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "no permission"})
+		return nil, roomOccurrence{}, false
+	}
+	occ, err := findRoomOccurrence(&room, time.UnixMilli(occurrenceID))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": err.Error()})
+		return nil, roomOccurrence{}, false
+	}
+	return &room, occ, true
+}
+
+// roomRule parses the room's recurrence rule; nil means a single meeting.
+func roomRule(room *models.RoomBasic) (*recurrence.Rule, error) {
+	if room.RRule == "" {
+		return nil, nil
+	}
+	return recurrence.Parse(room.RRule)
+}
+
+// parseRoomRule validates a rule submitted by a client and returns it in
+// canonical form ("" clears recurrence).
+func parseRoomRule(raw string) (string, error) {
+	if raw == "" {
+		return "", nil
+	}
+	rule, err := recurrence.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	return rule.String(), nil
+}
+
+// findRoomOccurrence returns the occurrence the rule generated at
+// originalStart, with any override applied.
+func findRoomOccurrence(room *models.RoomBasic, originalStart time.Time) (roomOccurrence, error) {
+	rule, err := roomRule(room)
+	if err != nil {
+		return roomOccurrence{}, err
+	}
+	if rule == nil {
+		return roomOccurrence{}, errors.New("room is not recurring")
+	}
+	if !rule.Includes(room.BeginAt, originalStart) {
+		return roomOccurrence{}, errNotAnOccurrence
+	}
+	occ := roomOccurrence{
+		OriginalStart: originalStart,
+		BeginAt:       originalStart,
+		EndAt:         originalStart.Add(room.EndAt.Sub(room.BeginAt)),
+	}
+	var override models.RoomOccurrence
+	if err := models.DB.Where("rid = ? AND original_start = ?", room.ID, originalStart).First(&override).Error; err == nil {
+		applyOccurrenceOverride(&occ, &override)
+	}
+	return occ, nil
+}
+
+func applyOccurrenceOverride(occ *roomOccurrence, override *models.RoomOccurrence) {
+	occ.BeginAt = override.BeginAt
+	occ.EndAt = override.EndAt
+	occ.Cancelled = override.Cancelled
+	occ.Overridden = true
+}
+
+func saveOccurrenceOverride(room *models.RoomBasic, occ roomOccurrence) error {
+	return models.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("rid = ? AND original_start = ?", room.ID, occ.OriginalStart).
+			Assign(models.RoomOccurrence{
+				BeginAt:   occ.BeginAt,
+				EndAt:     occ.EndAt,
+				Cancelled: occ.Cancelled,
+			}).
+			FirstOrCreate(&models.RoomOccurrence{
+				Rid:           room.ID,
+				OriginalStart: occ.OriginalStart,
+			}).Error
+		if err != nil {
+			return err
+		}
+		room.Sequence++
+		return tx.Model(room).Update("sequence", gorm.Expr("sequence + 1")).Error
+	})
+}
+
+// expandRoomOccurrences lists the room's occurrences overlapping [from, to),
+// ordered by begin time. Cancelled occurrences are left out unless asked for.
+func expandRoomOccurrences(room *models.RoomBasic, from, to time.Time, withCancelled bool) ([]roomOccurrence, error) {
+	rule, err := roomRule(room)
+	if err != nil {
+		return nil, err
+	}
+	if rule == nil {
+		if room.EndAt.After(from) && room.BeginAt.Before(to) {
+			return []roomOccurrence{{OriginalStart: room.BeginAt, BeginAt: room.BeginAt, EndAt: room.EndAt}}, nil
+		}
+		return nil, nil
+	}
+
+	var overrides []models.RoomOccurrence
+	if err := models.DB.Where("rid = ?", room.ID).Find(&overrides).Error; err != nil {
+		return nil, err
+	}
+	overrideByStart := make(map[int64]*models.RoomOccurrence, len(overrides))
+	for i := range overrides {
+		overrideByStart[overrides[i].OriginalStart.Unix()] = &overrides[i]
+	}
+
+	duration := room.EndAt.Sub(room.BeginAt)
+	var out []roomOccurrence
+	keep := func(occ roomOccurrence) {
+		if occ.Cancelled && !withCancelled {
+			return
+		}
+		if occ.EndAt.After(from) && occ.BeginAt.Before(to) {
+			out = append(out, occ)
+		}
+	}
+	for _, start := range rule.Between(room.BeginAt, from.Add(-duration), to) {
+		occ := roomOccurrence{OriginalStart: start, BeginAt: start, EndAt: start.Add(duration)}
+		if override, ok := overrideByStart[start.Unix()]; ok {
+			applyOccurrenceOverride(&occ, override)
+			delete(overrideByStart, start.Unix())
+		}
+		keep(occ)
+	}
+	// Occurrences rescheduled into the range from outside of it.
+	for _, override := range overrideByStart {
+		occ := roomOccurrence{OriginalStart: override.OriginalStart}
+		applyOccurrenceOverride(&occ, override)
+		keep(occ)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].BeginAt.Before(out[j].BeginAt) })
+	return out, nil
+}
+
+// upcomingRoomOccurrence returns the running or next occurrence at now.
+func upcomingRoomOccurrence(room *models.RoomBasic, now time.Time) (roomOccurrence, bool, error) {
+	occs, err := expandRoomOccurrences(room, now, now.Add(maxOccurrenceRange), false)
+	if err != nil || len(occs) == 0 {
+		return roomOccurrence{}, false, err
+	}
+	return occs[0], true, nil
+}
+
+// roomSeriesEnded reports whether every occurrence of the room is over.
+func roomSeriesEnded(room *models.RoomBasic, now time.Time) (bool, error) {
+	rule, err := roomRule(room)
+	if err != nil {
+		return false, err
+	}
+	if rule == nil {
+		return now.After(room.EndAt), nil
+	}
+	last, bounded := rule.Last(room.BeginAt)
+	if !bounded {
+		return false, nil
+	}
+	end := last.Add(room.EndAt.Sub(room.BeginAt))
+	var latest models.RoomOccurrence
+	if err := models.DB.Where("rid = ? AND cancelled = ?", room.ID, false).
+		Order("end_at desc").First(&latest).Error; err == nil && latest.EndAt.After(end) {
+		end = latest.EndAt
+	}
+	return now.After(end), nil
+}
+
+func occurrenceItem(room *models.RoomBasic, occ roomOccurrence) RoomOccurrenceItem {
+	return RoomOccurrenceItem{
+		Identity:     room.Identify,
+		OccurrenceID: occ.OriginalStart.UnixMilli(),
+		BeginAt:      occ.BeginAt,
+		EndAt:        occ.EndAt,
+		Overridden:   occ.Overridden,
+		Cancelled:    occ.Cancelled,
+	}
+}
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"GoMeetings/internal/define"
+	"GoMeetings/internal/helper"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// peerResume lets a connection that dropped without a close frame be picked
+// up again with its token (?session=) within the grace period. Meanwhile
+// the peer keeps its place in the room, messages addressed to it are
+// buffered and replayed on resume, and peer_left only goes out once the
+// grace period expires. The session lives on the node that held the
+// connection; reconnecting to another node ends it there and joins afresh.
+type peerResume struct {
+	token string
+
+	mu     sync.Mutex
+	parked bool
+	// ended is set once the session can no longer be resumed: the server
+	// closed it, or the buffer overflowed.
+	ended    bool
+	buffered [][]byte
+	timer    *time.Timer
+	// current is the connection holding the session now.
+	current *peerConn
+}
+
+func newPeerResume(peer *peerConn) *peerResume {
+	return &peerResume{token: helper.GenerateUUID(), current: peer}
+}
+
+func resumeGrace() time.Duration {
+	return helper.DurationFromEnv("SIGNAL_RESUME_GRACE", define.SignalResumeGrace)
+}
+
+// hold takes a payload sent to a finished connection of the session. The
+// current connection's payloads are buffered for a resume; those of a
+// connection that was already resumed go to its successor.
+func (r *peerResume) hold(from *peerConn, payload []byte) bool {
+	r.mu.Lock()
+	if r.ended {
+		r.mu.Unlock()
+		return false
+	}
+	if r.current == from {
+		if len(r.buffered) >= define.SignalResumeBuffer {
+			log.Printf("signal: resume buffer of %s is full, ending the session", from.identity())
+			r.endLocked()
+			r.mu.Unlock()
+			return false
+		}
+		r.buffered = append(r.buffered, payload)
+		r.mu.Unlock()
+		return true
+	}
+	next := r.current
+	r.mu.Unlock()
+	return next.sendBytes(payload) == nil
+}
+
+// park starts the grace period of the dropped peer. Messages still queued
+// for its writer are kept ahead of the buffered ones.
+func (r *peerResume) park(peer *peerConn, grace time.Duration, expire func()) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.ended || r.parked || r.current != peer {
+		return false
+	}
+	var pending [][]byte
+	for drained := false; !drained; {
+		select {
+		case msg := <-peer.send:
+			if !msg.close {
+				pending = append(pending, msg.payload)
+			}
+		default:
+			drained = true
+		}
+	}
+	r.buffered = append(pending, r.buffered...)
+	r.parked = true
+	r.timer = time.AfterFunc(grace, expire)
+	return true
+}
+
+// handOver moves the parked session to peer, which gets intro and then the
+// buffered messages before anything else. peer must not be serving yet.
+func (r *peerResume) handOver(peer *peerConn, intro ...[]byte) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.ended || !r.parked {
+		return false
+	}
+	r.timer.Stop()
+	r.parked = false
+	r.current = peer
+	for _, payload := range append(intro, r.buffered...) {
+		select {
+		case peer.send <- outbound{payload: payload}:
+		default:
+			log.Printf("signal: resume of %s dropped a buffered message", peer.identity())
+		}
+	}
+	r.buffered = nil
+	return true
+}
+
+// end makes the session final. A parked session expires right away.
+func (r *peerResume) end() {
+	r.mu.Lock()
+	r.endLocked()
+	r.mu.Unlock()
+}
+
+func (r *peerResume) endLocked() {
+	r.ended = true
+	r.buffered = nil
+	if r.parked && r.timer != nil {
+		r.timer.Reset(0)
+	}
+}
+
+// parkPeer keeps a dropped connection's place in the room for the grace
+// period instead of leaving.
+func (h *signalHub) parkPeer(peer *peerConn) bool {
+	r := peer.resume
+	if r == nil {
+		return false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.rooms[peer.currentRoom()][peer.identity()] != peer ||
+		!r.park(peer, resumeGrace(), func() { h.expireSession(peer) }) {
+		return false
+	}
+	h.parked[r.token] = peer
+	return true
+}
+
+// expireSession ends a parked session: the peer leaves the room for good.
+func (h *signalHub) expireSession(peer *peerConn) {
+	h.mu.Lock()
+	if h.parked[peer.resume.token] != peer {
+		// Resumed, or already expired.
+		h.mu.Unlock()
+		return
+	}
+	delete(h.parked, peer.resume.token)
+	roomIdentity := peer.currentRoom()
+	registered := h.rooms[roomIdentity][peer.identity()] == peer
+	if registered {
+		h.detachPeerLocked(roomIdentity, peer)
+	}
+	h.mu.Unlock()
+	peer.resume.end()
+	if !registered {
+		return
+	}
+
+	h.release(roomIdentity, peer)
+	recordPeerLeft(roomIdentity, peer)
+	h.notifyPeerLeft(roomIdentity, peer)
+}
+
+// expireParked ends a parked session of the connection identity right away,
+// so reconnecting without the token is not refused until the grace expires.
+func (h *signalHub) expireParked(roomIdentity, identity string) {
+	h.mu.RLock()
+	peer := h.rooms[roomIdentity][identity]
+	h.mu.RUnlock()
+	if peer != nil && peer.resume != nil {
+		h.expireSession(peer)
+	}
+}
+
+const (
+	// A join refused because another node holds a parked session of the
+	// identity retries the claim takeOverAttempts times, takeOverInterval
+	// apart, while that node expires it.
+	takeOverAttempts = 10
+	takeOverInterval = 50 * time.Millisecond
+)
+
+// takeOverParked handles a join whose identity is held by another node. It
+// asks that node to expire the session if it is parked and retries the
+// claim; a live connection there keeps the identity and the join stays
+// refused.
+func (h *signalHub) takeOverParked(roomIdentity string, peer *peerConn) (bool, error) {
+	identity := peer.identity()
+	remote := false
+	for _, m := range h.roomMembers(roomIdentity) {
+		if m.Identity == identity {
+			remote = m.Node != h.broker.Node()
+		}
+	}
+	if !remote {
+		return false, nil
+	}
+	h.publish(signalEnvelope{Op: opExpireSession, Room: roomIdentity, Target: identity})
+	for i := 0; i < takeOverAttempts; i++ {
+		time.Sleep(takeOverInterval)
+		claimed, err := h.claim(roomIdentity, peer)
+		if err != nil || claimed {
+			return claimed, err
+		}
+	}
+	return false, nil
+}
+
+// resumePeer attaches conn to the parked session of token. The session
+// continues in the room the peer is in now, which differs from the one it
+// connected to if it was moved to a breakout meanwhile.
+func (h *signalHub) resumePeer(token, userIdentity, device string, uid uint, protocol int, conn *websocket.Conn) (*peerConn, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	old, ok := h.parked[token]
+	if !ok || old.uid != uid || old.user != userIdentity || old.device != device || old.protocol != protocol {
+		return nil, false
+	}
+	roomIdentity := old.currentRoom()
+	if h.rooms[roomIdentity][old.identity()] != old {
+		return nil, false
+	}
+
+	peer := newPeerConn(conn, roomIdentity, old.user, device, uid, protocol)
+	peer.resume = old.resume
+	old.roomMu.RLock()
+	peer.sessionID = old.sessionID
+	old.roomMu.RUnlock()
+	if !old.resume.handOver(peer, welcomePayload(peer), sessionPayload(peer, true)) {
+		return nil, false
+	}
+	delete(h.parked, token)
+	h.rooms[roomIdentity][peer.identity()] = peer
+	return peer, true
+}
+
+// sendSession hands the peer the token to resume with.
+func sendSession(peer *peerConn) {
+	if err := peer.sendBytes(sessionPayload(peer, false)); err != nil {
+		log.Printf("signal: send session error: %v", err)
+	}
+}
+
+func sessionPayload(peer *peerConn, resumed bool) []byte {
+	return buildSystemPayload(peer.currentRoom(), "session", map[string]interface{}{
+		"token":         peer.resume.token,
+		"grace_seconds": int(resumeGrace().Seconds()),
+		"resumed":       resumed,
+	})
+}
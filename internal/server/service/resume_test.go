@@ -0,0 +1,143 @@
+package service
+
+import (
+	"GoMeetings/internal/broker"
+	"GoMeetings/internal/define"
+	"GoMeetings/internal/models"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func newTestPeer(queue int) *peerConn {
+	p := &peerConn{user: "alice", send: make(chan outbound, queue), done: make(chan struct{})}
+	p.resume = newPeerResume(p)
+	return p
+}
+
+func TestResumeReplaysInOrder(t *testing.T) {
+	old := newTestPeer(4)
+	_ = old.sendBytes([]byte("queued"))
+	old.finish()
+	if err := old.sendBytes([]byte("held")); err != nil {
+		t.Fatalf("send after drop: %v", err)
+	}
+	r := old.resume
+	if !r.park(old, time.Hour, func() {}) {
+		t.Fatal("park failed")
+	}
+
+	next := newTestPeer(8)
+	next.resume = r
+	if !r.handOver(next, []byte("welcome")) {
+		t.Fatal("hand over failed")
+	}
+	// A late delivery to the old connection reaches the new one.
+	if err := old.sendBytes([]byte("late")); err != nil {
+		t.Fatalf("send to resumed session: %v", err)
+	}
+	for _, want := range []string{"welcome", "queued", "held", "late"} {
+		if got := string((<-next.send).payload); got != want {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+}
+
+func TestResumeBufferOverflowExpires(t *testing.T) {
+	defer func(n int) { define.SignalResumeBuffer = n }(define.SignalResumeBuffer)
+	define.SignalResumeBuffer = 2
+
+	p := newTestPeer(1)
+	p.finish()
+	expired := make(chan struct{})
+	if !p.resume.park(p, time.Hour, func() { close(expired) }) {
+		t.Fatal("park failed")
+	}
+	for i := 0; i < 2; i++ {
+		if err := p.sendBytes([]byte("x")); err != nil {
+			t.Fatalf("send %d: %v", i, err)
+		}
+	}
+	if err := p.sendBytes([]byte("x")); err == nil {
+		t.Fatal("overflowing send should fail")
+	}
+	select {
+	case <-expired:
+	case <-time.After(time.Second):
+		t.Fatal("overflow should expire the session")
+	}
+	if p.resume.handOver(newTestPeer(4)) {
+		t.Fatal("an ended session was resumed")
+	}
+}
+
+// newRedisTestHub is a hub on its own node of the shared miniredis server.
+func newRedisTestHub(t *testing.T, srv *miniredis.Miniredis, node string) *signalHub {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+	b, err := broker.NewRedis(client, "test:", node)
+	if err != nil {
+		t.Fatalf("NewRedis(%s): %v", node, err)
+	}
+	h := newSignalHub(b)
+	h.runSlowOpsInBackground()
+	if err := h.subscribe(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = b.Close()
+		_ = client.Close()
+	})
+	return h
+}
+
+// useDryRunDB lets code that records attendance or webhooks run without a
+// database: statements are built but never sent.
+func useDryRunDB(t *testing.T) {
+	t.Helper()
+	db, err := gorm.Open(mysql.New(mysql.Config{DSN: "test@tcp(127.0.0.1:1)/test", SkipInitializeWithVersion: true}),
+		&gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := models.DB
+	models.DB = db
+	t.Cleanup(func() { models.DB = prev })
+}
+
+func TestJoinTakesOverSessionParkedOnAnotherNode(t *testing.T) {
+	useDryRunDB(t)
+	srv := miniredis.RunT(t)
+	a := newRedisTestHub(t, srv, "a")
+	b := newRedisTestHub(t, srv, "b")
+
+	old, _, err := a.join("r1", "alice", "", 1, protocolV2, nil)
+	if err != nil {
+		t.Fatalf("join on a: %v", err)
+	}
+	old.finish()
+	if !a.parkPeer(old) {
+		t.Fatal("park on a failed")
+	}
+
+	peer, _, err := b.join("r1", "alice", "", 1, protocolV2, nil)
+	if err != nil {
+		t.Fatalf("join on b while parked on a: %v", err)
+	}
+	members := b.roomMembers("r1")
+	if len(members) != 1 || members[0].Node != "b" {
+		t.Fatalf("members = %+v", members)
+	}
+	if old.resume.handOver(peer) {
+		t.Fatal("the session parked on a can still be resumed")
+	}
+
+	// A live connection keeps its identity.
+	if _, _, err := a.join("r1", "alice", "", 1, protocolV2, nil); err == nil {
+		t.Fatal("joined over a live connection on another node")
+	}
+}
@@ -3,11 +3,13 @@ package service
 import (
 	"GoMeetings/internal/helper"
 	"GoMeetings/internal/models"
+	"GoMeetings/internal/webhook"
 	"crypto/rand"
 	"errors"
 	"fmt"
 	"math/big"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -57,6 +59,9 @@ func generateCode(length int) string {
 // @Param page query int false "Page number"
 // @Param size query int false "Page size"
 // @Param keyword query string false "Keyword filter"
+// @Param from query int false "Expand occurrences from this time (ms)"
+// @Param to query int false "Expand occurrences until this time (ms)"
+// @Param status query string false "Comma-separated statuses: scheduled, live, ended, cancelled"
 // @Security BearerAuth
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
@@ -75,26 +80,26 @@ func RoomList(c *gin.Context) {
 		req.Size = 20
 	}
 
-	var userRooms []models.RoomUser
-	if err := models.DB.Where("uid = ?", uc.Id).Find(&userRooms).Error; err != nil {
+	query, joined, err := userRoomsQuery(uc.Id, time.Time{})
+	if err != nil {
 		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
 		return
 	}
-
-	joined := make(map[uint]bool, len(userRooms))
-	roomIDs := make([]uint, 0, len(userRooms))
-	for _, ur := range userRooms {
-		joined[ur.Rid] = true
-		roomIDs = append(roomIDs, ur.Rid)
-	}
-
-	query := models.DB.Model(&models.RoomBasic{}).Where("create_id = ?", uc.Id)
-	if len(roomIDs) > 0 {
-		query = query.Or("id IN ?", roomIDs)
-	}
 	if req.Keyword != "" {
 		query = query.Where("name LIKE ?", "%"+req.Keyword+"%")
 	}
+	statuses, err := parseRoomStatuses(req.Status)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	if len(statuses) > 0 {
+		query = query.Where("status IN ?", statuses)
+	}
+	if req.From > 0 || req.To > 0 {
+		roomOccurrenceList(c, uc, query, joined, req)
+		return
+	}
 
 	var total int64
 	if err := query.Count(&total).Error; err != nil {
@@ -119,6 +124,8 @@ func RoomList(c *gin.Context) {
 			EndAt:    room.EndAt,
 			CreateID: room.CreateID,
 			Joined:   joined[room.ID] || room.CreateID == uc.Id,
+			RRule:    room.RRule,
+			Status:   roomStatus(&room),
 		})
 	}
 
@@ -131,6 +138,97 @@ func RoomList(c *gin.Context) {
 	})
 }
 
+// userRoomsQuery selects the rooms uid created or is a member of, and
+// returns the ids of the joined ones. A non-zero deletedSince also selects
+// rooms and memberships deleted after it; those memberships map to false.
+func userRoomsQuery(uid uint, deletedSince time.Time) (*gorm.DB, map[uint]bool, error) {
+	memberships := models.DB.Where("uid = ?", uid)
+	if !deletedSince.IsZero() {
+		memberships = memberships.Unscoped().Where("deleted_at IS NULL OR deleted_at > ?", deletedSince)
+	}
+	var userRooms []models.RoomUser
+	if err := memberships.Find(&userRooms).Error; err != nil {
+		return nil, nil, err
+	}
+	joined := make(map[uint]bool, len(userRooms))
+	roomIDs := make([]uint, 0, len(userRooms))
+	for _, ur := range userRooms {
+		joined[ur.Rid] = joined[ur.Rid] || !ur.DeletedAt.Valid
+		roomIDs = append(roomIDs, ur.Rid)
+	}
+
+	owned := models.DB.Where("create_id = ?", uid)
+	if len(roomIDs) > 0 {
+		owned = owned.Or("id IN ?", roomIDs)
+	}
+	query := models.DB.Model(&models.RoomBasic{}).Where(owned).Where("parent_id = 0")
+	if !deletedSince.IsZero() {
+		query = query.Unscoped().Where("deleted_at IS NULL OR deleted_at > ?", deletedSince)
+	}
+	return query, joined, nil
+}
+
+// roomOccurrenceList answers RoomList with from/to: one item per occurrence
+// in the range, ordered by begin time and paginated over occurrences.
+func roomOccurrenceList(c *gin.Context, uc *helper.UserClaims, query *gorm.DB, joined map[uint]bool, req RoomListRequest) {
+	from, to := time.UnixMilli(req.From), time.UnixMilli(req.To)
+	if req.To <= 0 {
+		to = from.Add(maxOccurrenceRange)
+	}
+	if !to.After(from) || to.Sub(from) > maxOccurrenceRange {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "to must be after from and at most 366 days later"})
+		return
+	}
+
+	var rooms []models.RoomBasic
+	if err := query.Where("begin_at < ?", to).
+		Where(models.DB.Where("end_at > ?", from).Or("rrule <> ''")).
+		Find(&rooms).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+
+	list := make([]RoomListItem, 0, len(rooms))
+	for _, room := range rooms {
+		occs, err := expandRoomOccurrences(&room, from, to, false)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+			return
+		}
+		for _, occ := range occs {
+			list = append(list, RoomListItem{
+				Identity:     room.Identify,
+				Name:         room.Name,
+				BeginAt:      occ.BeginAt,
+				EndAt:        occ.EndAt,
+				CreateID:     room.CreateID,
+				Joined:       joined[room.ID] || room.CreateID == uc.Id,
+				RRule:        room.RRule,
+				Status:       roomStatus(&room),
+				OccurrenceID: occ.OriginalStart.UnixMilli(),
+			})
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].BeginAt.Before(list[j].BeginAt) })
+
+	total := int64(len(list))
+	start := (req.Page - 1) * req.Size
+	if start > len(list) {
+		start = len(list)
+	}
+	end := start + req.Size
+	if end > len(list) {
+		end = len(list)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": RoomListReply{
+			Total: total,
+			List:  list[start:end],
+		},
+	})
+}
+
 func roomMembersReply(c *gin.Context, uid uint, identity string) {
 	if identity == "" {
 		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "identity is required"})
@@ -158,11 +256,7 @@ func roomMembersReply(c *gin.Context, uid uint, identity string) {
 
 	memberList := make([]RoomMember, 0, len(members))
 	for _, m := range members {
-		memberList = append(memberList, RoomMember{
-			UserID:      m.Uid,
-			DisplayName: m.DisplayName,
-			JoinedAt:    m.CreatedAt.UnixMilli(),
-		})
+		memberList = append(memberList, roomMemberReply(&room, &m))
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -177,6 +271,7 @@ func roomMembersReply(c *gin.Context, uid uint, identity string) {
 					EndAt:    room.EndAt,
 					CreateID: room.CreateID,
 					Joined:   true,
+					Status:   roomStatus(&room),
 					Members:  memberList,
 				},
 			},
@@ -215,22 +310,11 @@ func RoomUserRooms(c *gin.Context) {
 		return
 	}
 
-	var userRooms []models.RoomUser
-	if err := models.DB.Where("uid = ?", targetID).Find(&userRooms).Error; err != nil {
+	query, joined, err := userRoomsQuery(targetID, time.Time{})
+	if err != nil {
 		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
 		return
 	}
-	joined := make(map[uint]bool, len(userRooms))
-	roomIDs := make([]uint, 0, len(userRooms))
-	for _, ur := range userRooms {
-		joined[ur.Rid] = true
-		roomIDs = append(roomIDs, ur.Rid)
-	}
-
-	query := models.DB.Model(&models.RoomBasic{}).Where("create_id = ?", targetID)
-	if len(roomIDs) > 0 {
-		query = query.Or("id IN ?", roomIDs)
-	}
 	if req.Keyword != "" {
 		query = query.Where("name LIKE ?", "%"+req.Keyword+"%")
 	}
@@ -248,11 +332,7 @@ func RoomUserRooms(c *gin.Context) {
 		return
 	}
 
-	roomIDList := make([]uint, 0, len(rooms))
-	for _, room := range rooms {
-		roomIDList = append(roomIDList, room.ID)
-	}
-	memberMap, err := loadMembersForRooms(roomIDList)
+	memberMap, err := loadMembersForRooms(rooms)
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
 		return
@@ -267,6 +347,8 @@ func RoomUserRooms(c *gin.Context) {
 			EndAt:    room.EndAt,
 			CreateID: room.CreateID,
 			Joined:   joined[room.ID] || room.CreateID == targetID,
+			RRule:    room.RRule,
+			Status:   roomStatus(&room),
 			Members:  memberMap[room.ID],
 		})
 	}
@@ -292,6 +374,8 @@ func RoomUserRooms(c *gin.Context) {
 // @Param join_code formData string false "Custom join code"
 // @Param short_code formData string false "Short code"
 // @Param display_name formData string false "Owner display name"
+// @Param lobby_enabled formData boolean false "Hold joiners in a lobby until admitted"
+// @Param rrule formData string false "Recurrence rule, e.g. FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
 // @Router /auth/room/create [post]
@@ -306,6 +390,10 @@ func RoomCreate(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "end time must be greater than begin time"})
 		return
 	}
+	if strings.Contains(req.DisplayName, "/") {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": errDisplayNameSlash.Error()})
+		return
+	}
 
 	joinCode, err := ensureUniqueJoinCode(req.JoinCode, 0)
 	if err != nil {
@@ -321,6 +409,11 @@ func RoomCreate(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "unable to allocate short code: " + err.Error()})
 		return
 	}
+	rrule, err := parseRoomRule(req.RRule)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
 
 	room := models.RoomBasic{
 		Identify:  helper.GenerateUUID(),
@@ -330,11 +423,15 @@ func RoomCreate(c *gin.Context) {
 		CreateID:  uc.Id,
 		JoinCode:  joinCode,
 		ShortCode: shortCode,
+		Lobby:     req.LobbyEnabled,
+		RRule:     rrule,
+		Status:    models.RoomStatusScheduled,
 	}
 	if err := models.DB.Create(&room).Error; err != nil {
 		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
 		return
 	}
+	emitWebhookEvent(webhook.RoomCreated, roomWebhookData(&room))
 
 	ownerName := req.DisplayName
 	if ownerName == "" {
@@ -345,10 +442,12 @@ func RoomCreate(c *gin.Context) {
 	}
 	_ = models.DB.Where("rid = ? AND uid = ?", room.ID, uc.Id).Assign(models.RoomUser{
 		DisplayName: ownerName,
+		Role:        models.RoomRoleHost,
 	}).FirstOrCreate(&models.RoomUser{
 		Rid:         room.ID,
 		Uid:         uc.Id,
 		DisplayName: ownerName,
+		Role:        models.RoomRoleHost,
 	})
 
 	c.JSON(http.StatusOK, gin.H{"code": 200, "data": room})
@@ -366,6 +465,8 @@ func RoomCreate(c *gin.Context) {
 // @Param end_at formData integer true "End time (ms)"
 // @Param join_code formData string false "Custom join code"
 // @Param short_code formData string false "Short code"
+// @Param lobby_enabled formData boolean false "Hold joiners in a lobby until admitted"
+// @Param rrule formData string false "Recurrence rule; empty makes the room a single meeting"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
 // @Router /auth/room/edit [put]
@@ -381,7 +482,7 @@ func RoomEdit(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "room not found"})
 		return
 	}
-	if room.CreateID != uc.Id {
+	if !hasRoomPermission(&room, uc.Id, permEditRoom) {
 		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "no permission"})
 		return
 	}
@@ -390,6 +491,7 @@ func RoomEdit(c *gin.Context) {
 		"name":     req.Name,
 		"begin_at": time.UnixMilli(req.BeginAt),
 		"end_at":   time.UnixMilli(req.EndAt),
+		"sequence": gorm.Expr("sequence + 1"),
 	}
 	if req.JoinCode != "" {
 		code, err := ensureUniqueJoinCode(req.JoinCode, room.ID)
@@ -413,13 +515,44 @@ func RoomEdit(c *gin.Context) {
 		update["short_code"] = code
 		room.ShortCode = code
 	}
-	if err := models.DB.Model(&room).Updates(update).Error; err != nil {
+	if req.LobbyEnabled != nil {
+		update["lobby_enabled"] = *req.LobbyEnabled
+		room.Lobby = *req.LobbyEnabled
+	}
+	rrule := room.RRule
+	if req.RRule != nil {
+		parsed, err := parseRoomRule(*req.RRule)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+			return
+		}
+		rrule = parsed
+		update["rrule"] = rrule
+	}
+	// Overrides are keyed by generated starts, which no longer line up once
+	// the rule or the series start changes. begin_at is stored with second
+	// precision, so milliseconds in the request do not count as a change.
+	resetOccurrences := rrule != room.RRule ||
+		!time.UnixMilli(req.BeginAt).Truncate(time.Second).Equal(room.BeginAt.Truncate(time.Second))
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&room).Updates(update).Error; err != nil {
+			return err
+		}
+		if resetOccurrences {
+			return tx.Unscoped().Where("rid = ?", room.ID).Delete(&models.RoomOccurrence{}).Error
+		}
+		return nil
+	})
+	if err != nil {
 		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
 		return
 	}
+	room.RRule = rrule
+	room.Sequence++
 	room.Name = req.Name
 	room.BeginAt = time.UnixMilli(req.BeginAt)
 	room.EndAt = time.UnixMilli(req.EndAt)
+	emitWebhookEvent(webhook.RoomUpdated, roomWebhookData(&room))
 
 	c.JSON(http.StatusOK, gin.H{"code": 200, "data": room})
 }
@@ -446,16 +579,24 @@ func RoomDelete(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "room not found"})
 		return
 	}
-	if room.CreateID != uc.Id {
+	if !hasRoomPermission(&room, uc.Id, permDeleteRoom) {
 		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "no permission"})
 		return
 	}
 
-	if err := models.DB.Delete(&room).Error; err != nil {
+	// The bumped sequence lets calendar clients apply the cancellation.
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&room).Update("sequence", gorm.Expr("sequence + 1")).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&room).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
 		return
 	}
 	models.DB.Where("rid = ?", room.ID).Delete(&models.RoomUser{})
+	emitWebhookEvent(webhook.RoomDeleted, roomWebhookData(&room))
 
 	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "delete success"})
 }
@@ -485,20 +626,104 @@ func RoomJoin(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "room not found"})
 		return
 	}
-	if err := ensureRoomJoinWindow(&room, time.Now()); err != nil {
+	joinRoom(c, uc, &room, req.JoinCode, req.DisplayName)
+}
+
+// RoomJoinByCode godoc
+// @Summary Join room by short code
+// @Description Same as /auth/room/join but addresses the room by its short code
+// @Tags Room
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param short_code formData string true "Room short code"
+// @Param display_name formData string true "Display name"
+// @Param join_code formData string true "Join code"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /auth/room/join-by-code [post]
+func RoomJoinByCode(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	req := RoomJoinByCodeRequest{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+
+	room, err := findRoomByShortCode(req.ShortCode)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "room not found"})
+		return
+	}
+	joinRoom(c, uc, room, req.JoinCode, req.DisplayName)
+}
+
+// RoomLookup godoc
+// @Summary Look up room by short code
+// @Description Public room metadata for a short code such as "ABC123"
+// @Tags Room
+// @Produce json
+// @Param short_code query string true "Room short code"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /auth/room/lookup [get]
+func RoomLookup(c *gin.Context) {
+	code := c.Query("short_code")
+	if strings.TrimSpace(code) == "" {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "short_code is required"})
+		return
+	}
+	room, err := findRoomByShortCode(code)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "room not found"})
+		return
+	}
+
+	beginAt, endAt := room.BeginAt, room.EndAt
+	if room.RRule != "" {
+		if occ, ok, err := upcomingRoomOccurrence(room, time.Now()); err == nil && ok {
+			beginAt, endAt = occ.BeginAt, occ.EndAt
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": RoomPublicInfo{
+			Identity:         room.Identify,
+			Name:             room.Name,
+			ShortCode:        room.ShortCode,
+			BeginAt:          beginAt,
+			EndAt:            endAt,
+			RRule:            room.RRule,
+			JoinCodeRequired: room.JoinCode != "",
+			LobbyEnabled:     room.Lobby,
+			Locked:           room.Locked,
+			Status:           roomStatus(room),
+			Open:             !room.Locked && ensureRoomJoinWindow(room, time.Now()) == nil,
+		},
+	})
+}
+
+func joinRoom(c *gin.Context, uc *helper.UserClaims, room *models.RoomBasic, joinCode, displayName string) {
+	if err := ensureRoomJoinWindow(room, time.Now()); err != nil {
 		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": err.Error()})
 		return
 	}
-	if strings.ToUpper(req.JoinCode) != room.JoinCode {
+	if strings.ToUpper(strings.TrimSpace(joinCode)) != room.JoinCode {
 		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "join code incorrect"})
 		return
 	}
 
-	displayName := strings.TrimSpace(req.DisplayName)
+	displayName = strings.TrimSpace(displayName)
 	if displayName == "" {
 		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "display name is required"})
 		return
 	}
+	if strings.Contains(displayName, "/") {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": errDisplayNameSlash.Error()})
+		return
+	}
+	data := gin.H{"identity": room.Identify}
 
 	var roomUser models.RoomUser
 	result := models.DB.Where("rid = ? AND uid = ?", room.ID, uc.Id).First(&roomUser)
@@ -506,7 +731,30 @@ func RoomJoin(c *gin.Context) {
 		if displayName != roomUser.DisplayName {
 			models.DB.Model(&roomUser).Update("display_name", displayName)
 		}
-		c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "already joined"})
+		c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "already joined", "data": data})
+		return
+	}
+
+	if err := checkRoomAdmission(room, uc.Id); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+
+	if lobbyPending(room, uc.Id) {
+		entry, isNew, err := requestLobbyAdmission(models.DB, room, uc.Id, displayName, false)
+		if errors.Is(err, errLobbyDenied) {
+			c.JSON(http.StatusOK, gin.H{"code": -1, "msg": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+			return
+		}
+		if isNew {
+			announceLobbyRequest(room, entry)
+		}
+		data["lobby_status"] = models.LobbyStatusPending
+		c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "waiting for host approval", "data": data})
 		return
 	}
 
@@ -519,7 +767,7 @@ func RoomJoin(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "join success"})
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "join success", "data": data})
 }
 
 // RoomLeave godoc
@@ -594,11 +842,7 @@ func RoomMembers(c *gin.Context) {
 	}
 	list := make([]RoomMember, 0, len(members))
 	for _, m := range members {
-		list = append(list, RoomMember{
-			UserID:      m.Uid,
-			DisplayName: m.DisplayName,
-			JoinedAt:    m.CreatedAt.UnixMilli(),
-		})
+		list = append(list, roomMemberReply(&room, &m))
 	}
 	c.JSON(http.StatusOK, gin.H{
 		"code": 200,
@@ -640,14 +884,45 @@ func ensureUniqueRoomCode(column, candidate string, length int, excludeID uint)
 	return "", fmt.Errorf("unable to generate unique %s", column)
 }
 
+// ensureRoomJoinWindow checks that the room is neither cancelled nor ended
+// and that now falls in an occurrence of it, opening roomEarlyJoinWindow
+// before it begins.
 func ensureRoomJoinWindow(room *models.RoomBasic, now time.Time) error {
-	if now.After(room.EndAt) {
-		return errors.New("meeting has already ended")
+	if room.ParentID != 0 {
+		parent, err := breakoutParent(room)
+		if err != nil {
+			return errors.New("main room not found")
+		}
+		return ensureRoomJoinWindow(parent, now)
 	}
-	if now.Before(room.BeginAt.Add(-roomEarlyJoinWindow)) {
-		return errors.New("meeting is not open for participants yet")
+	if err := ensureRoomNotClosed(room); err != nil {
+		return err
+	}
+	if room.RRule == "" {
+		if now.After(room.EndAt) {
+			return errors.New("meeting has already ended")
+		}
+		if now.Before(room.BeginAt.Add(-roomEarlyJoinWindow)) {
+			return errors.New("meeting is not open for participants yet")
+		}
+		return nil
+	}
+
+	occs, err := expandRoomOccurrences(room, now, now.Add(roomEarlyJoinWindow), false)
+	if err != nil {
+		return err
+	}
+	if len(occs) > 0 {
+		return nil
+	}
+	ended, err := roomSeriesEnded(room, now)
+	if err != nil {
+		return err
 	}
-	return nil
+	if ended {
+		return errors.New("meeting has already ended")
+	}
+	return errors.New("meeting is not open for participants yet")
 }
 
 func resolveUserIdentity(identity string) (uint, error) {
@@ -664,25 +939,37 @@ func resolveUserIdentity(identity string) (uint, error) {
 	return user.ID, nil
 }
 
-func loadMembersForRooms(roomIDs []uint) (map[uint][]RoomMember, error) {
+func loadMembersForRooms(rooms []models.RoomBasic) (map[uint][]RoomMember, error) {
 	result := make(map[uint][]RoomMember)
-	if len(roomIDs) == 0 {
+	if len(rooms) == 0 {
 		return result, nil
 	}
+	roomByID := make(map[uint]*models.RoomBasic, len(rooms))
+	roomIDs := make([]uint, 0, len(rooms))
+	for i := range rooms {
+		roomByID[rooms[i].ID] = &rooms[i]
+		roomIDs = append(roomIDs, rooms[i].ID)
+	}
 	var roomMembers []models.RoomUser
 	if err := models.DB.Where("rid IN ?", roomIDs).Find(&roomMembers).Error; err != nil {
 		return nil, err
 	}
 	for _, m := range roomMembers {
-		result[m.Rid] = append(result[m.Rid], RoomMember{
-			UserID:      m.Uid,
-			DisplayName: m.DisplayName,
-			JoinedAt:    m.CreatedAt.UnixMilli(),
-		})
+		result[m.Rid] = append(result[m.Rid], roomMemberReply(roomByID[m.Rid], &m))
 	}
 	return result, nil
 }
 
+func roomMemberReply(room *models.RoomBasic, m *models.RoomUser) RoomMember {
+	return RoomMember{
+		UserID:      m.Uid,
+		DisplayName: m.DisplayName,
+		JoinedAt:    m.CreatedAt.UnixMilli(),
+		Guest:       m.IsGuest,
+		Role:        effectiveRole(room, m),
+	}
+}
+
 // RoomShareStart godoc
 // @Summary Start screen sharing
 // @Tags Room
@@ -701,16 +988,24 @@ func RoomShareStart(c *gin.Context) {
 		return
 	}
 
-	room, membership, ok := loadRoomAndMembership(c, uc.Id, req.Identity)
+	room, membership, ok := loadRoomAndMembership(c, uc, req.Identity)
 	if !ok {
 		return
 	}
 	ownerName := resolveDisplayName(membership, uc.Name)
+	if !hasRoomPermission(room, uc.Id, permShareScreen) {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "your role is not allowed to share the screen"})
+		return
+	}
 
 	if err := ensureRoomJoinWindow(room, time.Now()); err != nil {
 		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": err.Error()})
 		return
 	}
+	if err := ensureMeetingLive(room); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
 
 	var existing models.RoomScreenShare
 	err := models.DB.Where("rid = ? AND active = ?", room.ID, true).First(&existing).Error
@@ -783,7 +1078,7 @@ func RoomShareStop(c *gin.Context) {
 		return
 	}
 
-	room, _, ok := loadRoomAndMembership(c, uc.Id, req.Identity)
+	room, _, ok := loadRoomAndMembership(c, uc, req.Identity)
 	if !ok {
 		return
 	}
@@ -798,7 +1093,7 @@ func RoomShareStop(c *gin.Context) {
 		return
 	}
 
-	if share.OwnerUid != uc.Id && room.CreateID != uc.Id {
+	if share.OwnerUid != uc.Id && !hasRoomPermission(room, uc.Id, permStopAnyShare) {
 		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "no permission to stop screen share"})
 		return
 	}
@@ -832,7 +1127,7 @@ func RoomShareStatus(c *gin.Context) {
 		return
 	}
 
-	room, _, ok := loadRoomAndMembership(c, uc.Id, identity)
+	room, _, ok := loadRoomAndMembership(c, uc, identity)
 	if !ok {
 		return
 	}
@@ -866,7 +1161,12 @@ func RoomShareStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"code": 200, "data": resp})
 }
 
-func loadRoomAndMembership(c *gin.Context, uid uint, identity string) (*models.RoomBasic, *models.RoomUser, bool) {
+func loadRoomAndMembership(c *gin.Context, uc *helper.UserClaims, identity string) (*models.RoomBasic, *models.RoomUser, bool) {
+	if !guestScopeAllows(uc, identity) {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "no permission"})
+		return nil, nil, false
+	}
+	uid := uc.Id
 	var room models.RoomBasic
 	if err := models.DB.Where("identify = ?", identity).First(&room).Error; err != nil {
 		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "room not found"})
@@ -918,3 +1218,34 @@ func resolveDisplayName(m *models.RoomUser, fallback string) string {
 	}
 	return "host"
 }
+
+// RoomRoleUpdate godoc
+// @Summary Change a participant's role
+// @Description Promote or demote a participant (cohost, presenter, attendee). Only the host can change co-hosts.
+// @Tags Room
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param identity formData string true "Room identity"
+// @Param user_id formData integer true "Participant user ID"
+// @Param role formData string true "New role"
+// @Success 200 {object} map[string]string
+// @Router /auth/room/role [post]
+func RoomRoleUpdate(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	req := RoomRoleRequest{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	var room models.RoomBasic
+	if err := models.DB.Where("identify = ?", req.Identity).First(&room).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "room not found"})
+		return
+	}
+	if err := changeRoomRole(&room, uc.Id, req.UserID, strings.ToLower(strings.TrimSpace(req.Role))); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "role updated"})
+}
@@ -0,0 +1,263 @@
+package service
+
+import (
+	"GoMeetings/internal/define"
+	"GoMeetings/internal/helper"
+	"GoMeetings/internal/models"
+	"GoMeetings/internal/webhook"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// meetingLookback bounds how far back a recurring room's running occurrence
+// is searched for.
+const meetingLookback = 24 * time.Hour
+
+func meetingEndWarning() time.Duration {
+	return helper.DurationFromEnv("MEETING_END_WARNING", define.MeetingEndWarning)
+}
+
+// schedulerLease is the broker lease held by the one node that runs the
+// scheduler, so a cluster warns and closes each meeting once.
+const schedulerLease = "meeting-scheduler"
+
+// StartMeetingScheduler enforces end times of running meetings in the
+// background: peers are warned with meeting_ending shortly before the end
+// and the meeting is closed once it is reached. Rooms that were never
+// started end once their time is over. With several instances only the
+// holder of the scheduler lease does this; another takes over within a few
+// intervals if it goes away.
+func StartMeetingScheduler(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(define.MeetingSchedulerInterval)
+		defer ticker.Stop()
+		warned := make(map[uint]time.Time)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if holdsSchedulerLease() {
+				now := time.Now()
+				enforceMeetingEnds(now, warned)
+				expireUnstartedMeetings(now)
+			}
+		}
+	}()
+}
+
+func holdsSchedulerLease() bool {
+	ctx, cancel := brokerContext()
+	defer cancel()
+	ok, err := wsHub.broker.Lease(ctx, schedulerLease, 3*define.MeetingSchedulerInterval)
+	if err != nil {
+		log.Printf("scheduler: lease: %v", err)
+		return false
+	}
+	return ok
+}
+
+// enforceMeetingEnds runs one scheduler pass. warned remembers the end time
+// each room was last warned about, so an extension warns again.
+func enforceMeetingEnds(now time.Time, warned map[uint]time.Time) {
+	rooms, err := roomsInProgress()
+	if err != nil {
+		log.Printf("scheduler: load running rooms: %v", err)
+		return
+	}
+	seen := make(map[uint]bool, len(rooms))
+	for i := range rooms {
+		room := &rooms[i]
+		seen[room.ID] = true
+		occ, ok, err := currentMeeting(room, now)
+		if err != nil {
+			log.Printf("scheduler: room %s: %v", room.Identify, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if !now.Before(occ.EndAt) {
+			delete(warned, room.ID)
+			if err := closeMeeting(room, "scheduled_end", now); err != nil {
+				log.Printf("scheduler: end room %s: %v", room.Identify, err)
+			}
+			continue
+		}
+		if occ.EndAt.Sub(now) <= meetingEndWarning() && !warned[room.ID].Equal(occ.EndAt) {
+			warned[room.ID] = occ.EndAt
+			notifyMeetingAndBreakouts(room, "meeting_ending", map[string]interface{}{
+				"ends_at":      occ.EndAt.UnixMilli(),
+				"seconds_left": int(occ.EndAt.Sub(now) / time.Second),
+			})
+		}
+	}
+	for id := range warned {
+		if !seen[id] {
+			delete(warned, id)
+		}
+	}
+}
+
+// roomsInProgress returns the main rooms that are live or have connected
+// peers or an active screen share, in themselves or in one of their breakouts.
+func roomsInProgress() ([]models.RoomBasic, error) {
+	identities := make([]string, 0)
+	for identity := range wsHub.snapshot() {
+		identities = append(identities, identity)
+	}
+	var sharing []uint
+	if err := models.DB.Model(&models.RoomScreenShare{}).Where("active = ?", true).
+		Distinct().Pluck("rid", &sharing).Error; err != nil {
+		return nil, err
+	}
+	query := models.DB.Where("status = ? AND parent_id = 0", models.RoomStatusLive)
+	if len(identities) > 0 {
+		query = query.Or("identify IN ?", identities)
+	}
+	if len(sharing) > 0 {
+		query = query.Or("id IN ?", sharing)
+	}
+	var found []models.RoomBasic
+	if err := models.DB.Where(query).Find(&found).Error; err != nil {
+		return nil, err
+	}
+
+	rooms := make([]models.RoomBasic, 0, len(found))
+	ids := make(map[uint]bool, len(found))
+	var parentIDs []uint
+	for _, room := range found {
+		if room.ParentID != 0 {
+			parentIDs = append(parentIDs, room.ParentID)
+			continue
+		}
+		if !ids[room.ID] {
+			ids[room.ID] = true
+			rooms = append(rooms, room)
+		}
+	}
+	if len(parentIDs) > 0 {
+		var parents []models.RoomBasic
+		if err := models.DB.Where("id IN ?", parentIDs).Find(&parents).Error; err != nil {
+			return nil, err
+		}
+		for _, room := range parents {
+			if !ids[room.ID] {
+				ids[room.ID] = true
+				rooms = append(rooms, room)
+			}
+		}
+	}
+	return rooms, nil
+}
+
+// currentMeeting returns the occurrence of the room that is open at now
+// (including the early join window) or most recently was.
+func currentMeeting(room *models.RoomBasic, now time.Time) (roomOccurrence, bool, error) {
+	if room.RRule == "" {
+		if now.Before(room.BeginAt.Add(-roomEarlyJoinWindow)) {
+			return roomOccurrence{}, false, nil
+		}
+		return roomOccurrence{OriginalStart: room.BeginAt, BeginAt: room.BeginAt, EndAt: room.EndAt}, true, nil
+	}
+	occs, err := expandRoomOccurrences(room, now.Add(-meetingLookback), now.Add(roomEarlyJoinWindow), false)
+	if err != nil {
+		return roomOccurrence{}, false, err
+	}
+	for i := len(occs) - 1; i >= 0; i-- {
+		if !now.Before(occs[i].BeginAt.Add(-roomEarlyJoinWindow)) {
+			return occs[i], true, nil
+		}
+	}
+	return roomOccurrence{}, false, nil
+}
+
+// notifyMeetingAndBreakouts broadcasts a system event to the room and all of
+// its breakout rooms.
+func notifyMeetingAndBreakouts(room *models.RoomBasic, key string, value interface{}) {
+	notifyRoomEvent(room.Identify, key, value)
+	for _, child := range breakoutIdentities(room) {
+		notifyRoomEvent(child, key, value)
+	}
+}
+
+// RoomExtend godoc
+// @Summary Extend a running meeting
+// @Description Pushes back the end of the running meeting (or occurrence) and broadcasts meeting_extended
+// @Tags Room
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param identity formData string true "Room identity"
+// @Param minutes formData integer true "Minutes to add"
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/room/extend [post]
+func RoomExtend(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	req := RoomExtendRequest{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	extension := time.Duration(req.Minutes) * time.Minute
+	if extension <= 0 || extension > define.MaxMeetingExtension {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": fmt.Sprintf("minutes must be between 1 and %d", int(define.MaxMeetingExtension/time.Minute))})
+		return
+	}
+	var room models.RoomBasic
+	if err := models.DB.Where("identify = ?", req.Identity).First(&room).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "room not found"})
+		return
+	}
+	if room.ParentID != 0 {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "extend the main room instead"})
+		return
+	}
+	if !hasRoomPermission(&room, uc.Id, permEditRoom) {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "no permission"})
+		return
+	}
+
+	now := time.Now()
+	occ, ok, err := currentMeeting(&room, now)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	if !ok || !now.Before(occ.EndAt) {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "meeting is not in progress"})
+		return
+	}
+
+	occ.EndAt = occ.EndAt.Add(extension)
+	if room.RRule != "" {
+		err = saveOccurrenceOverride(&room, occ)
+	} else {
+		err = models.DB.Model(&room).Updates(map[string]interface{}{
+			"end_at":   occ.EndAt,
+			"sequence": gorm.Expr("sequence + 1"),
+		}).Error
+		room.EndAt = occ.EndAt
+		room.Sequence++
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+
+	value := map[string]interface{}{
+		"ends_at":     occ.EndAt.UnixMilli(),
+		"minutes":     req.Minutes,
+		"extended_by": uc.Id,
+	}
+	notifyMeetingAndBreakouts(&room, "meeting_extended", value)
+	emitWebhookEvent(webhook.RoomUpdated, roomWebhookData(&room))
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": value})
+}
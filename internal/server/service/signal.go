@@ -9,13 +9,17 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"os"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"GoMeetings/internal/broker"
 	"GoMeetings/internal/helper"
 	"GoMeetings/internal/models"
+	"GoMeetings/internal/webhook"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
@@ -30,11 +34,41 @@ type signalMessage struct {
 	TargetIdentity string          `json:"target_identity,omitempty"`
 	Timestamp      int64           `json:"timestamp"`
 	System         bool            `json:"system,omitempty"`
+	// PeerIdentity is the sending connection, which differs from
+	// UserIdentity when the user connected with a device id.
+	PeerIdentity string `json:"peer_identity,omitempty"`
 }
 
 const (
 	maxSignalPayloadSize = 64 * 1024 // 64KB
 	pongWaitDuration     = 70 * time.Second
+	// Pings go out often enough that a healthy client's pong always lands
+	// within pongWaitDuration.
+	pingPeriod        = pongWaitDuration * 9 / 10
+	writeWait         = 10 * time.Second
+	closeWriteTimeout = time.Second
+	// sendQueueSize bounds the messages waiting for a peer's writer; a peer
+	// that falls this far behind is a slow consumer.
+	sendQueueSize = 256
+)
+
+// Slow-consumer policies (SIGNAL_SLOW_CONSUMER).
+const (
+	slowConsumerDisconnect = "disconnect"
+	slowConsumerDrop       = "drop"
+)
+
+var (
+	errPeerClosed    = errors.New("connection closed")
+	errSendQueueFull = errors.New("send queue full")
+)
+
+// Application websocket close codes (4000-4999 are reserved for private use).
+const (
+	closeMeetingEnded    = 4000
+	closeAccountDisabled = 4001
+	closeRemovedByHost   = 4002
+	closeSlowConsumer    = 4003
 )
 
 var wsUpgrader = websocket.Upgrader{
@@ -54,25 +88,201 @@ var wsUpgrader = websocket.Upgrader{
 
 type peerConn struct {
 	conn    *websocket.Conn
-	room    string
+	room    string // guarded by roomMu; changes when moved to a breakout
 	user    string
-	writeMu sync.Mutex
+	device  string // ?device= of the connection, empty when not given
+	uid     uint
+	inLobby bool // guarded by signalHub.mu
+	roomMu  sync.RWMutex
+	// protocol is the signaling protocol version chosen on connect.
+	protocol int
+
+	// Only writeLoop writes to conn. send is its queue; done is closed once
+	// the connection is finished.
+	send         chan outbound
+	done         chan struct{}
+	doneOnce     sync.Once
+	dropWhenFull bool
+	dropping     atomic.Bool
+
+	// sessionID is the attendance row of the current room, guarded by roomMu.
+	sessionID uint
+	// resume lets the connection be resumed if it drops while in a room.
+	resume *peerResume
+
+	// Reaction rate limiting, see allowReaction.
+	reactionMu    sync.Mutex
+	reactionStart time.Time
+	reactionCount int
 }
 
+// identity addresses the connection in its room: the user identity, suffixed
+// with "/device" when the user connected with a device id, so one user can
+// be in a room from several devices.
+func (p *peerConn) identity() string {
+	return peerIdentity(p.user, p.device)
+}
+
+func peerIdentity(userIdentity, device string) string {
+	if device == "" {
+		return userIdentity
+	}
+	return userIdentity + "/" + device
+}
+
+// currentRoom is the signaling group the peer is in right now.
+func (p *peerConn) currentRoom() string {
+	p.roomMu.RLock()
+	defer p.roomMu.RUnlock()
+	return p.room
+}
+
+func (p *peerConn) setRoom(roomIdentity string) {
+	p.roomMu.Lock()
+	p.room = roomIdentity
+	p.roomMu.Unlock()
+}
+
+// outbound is one entry of a peer's send queue: a text message, or the close
+// frame that ends the connection once everything before it is written.
+type outbound struct {
+	payload     []byte
+	close       bool
+	closeCode   int
+	closeReason string
+}
+
+func newPeerConn(conn *websocket.Conn, roomIdentity, userIdentity, device string, uid uint, protocol int) *peerConn {
+	p := &peerConn{
+		conn:         conn,
+		room:         roomIdentity,
+		user:         userIdentity,
+		device:       device,
+		uid:          uid,
+		protocol:     protocol,
+		send:         make(chan outbound, sendQueueSize),
+		done:         make(chan struct{}),
+		dropWhenFull: os.Getenv("SIGNAL_SLOW_CONSUMER") == slowConsumerDrop,
+	}
+	p.resume = newPeerResume(p)
+	return p
+}
+
+// serve runs the connection until it ends: writes on their own goroutine,
+// reads on the caller's.
+func (p *peerConn) serve(hub *signalHub) {
+	go p.writeLoop()
+	p.readLoop(hub)
+}
+
+// sendBytes queues payload without blocking, so a slow client never stalls
+// the sender. A full queue applies the slow-consumer policy.
 func (p *peerConn) sendBytes(payload []byte) error {
-	p.writeMu.Lock()
-	defer p.writeMu.Unlock()
-	return p.conn.WriteMessage(websocket.TextMessage, payload)
+	select {
+	case <-p.done:
+		if p.resume != nil && p.resume.hold(p, payload) {
+			return nil
+		}
+		return errPeerClosed
+	default:
+	}
+	select {
+	case p.send <- outbound{payload: payload}:
+		p.dropping.Store(false)
+		return nil
+	default:
+	}
+
+	if p.dropWhenFull {
+		if !p.dropping.Swap(true) {
+			log.Printf("signal: slow consumer %s in %s, dropping messages", p.user, p.currentRoom())
+		}
+		return errSendQueueFull
+	}
+	log.Printf("signal: slow consumer %s in %s, disconnecting (%d messages queued)", p.user, p.currentRoom(), len(p.send))
+	p.closeNow(closeSlowConsumer, "slow consumer")
+	return errSendQueueFull
+}
+
+// close sends a close frame with the given code after the messages already
+// queued and drops the connection. The read loop then exits and runs the
+// normal leave handling; the session cannot be resumed.
+func (p *peerConn) close(code int, reason string) {
+	p.endResume()
+	select {
+	case p.send <- outbound{close: true, closeCode: code, closeReason: reason}:
+	default:
+		p.closeNow(code, reason)
+	}
+}
+
+// closeNow skips the queue. WriteControl may run concurrently with the
+// writer.
+func (p *peerConn) closeNow(code int, reason string) {
+	p.endResume()
+	_ = p.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(closeWriteTimeout))
+	_ = p.conn.Close()
+	p.finish()
+}
+
+func (p *peerConn) finish() {
+	p.doneOnce.Do(func() { close(p.done) })
+}
+
+func (p *peerConn) endResume() {
+	if p.resume != nil {
+		p.resume.end()
+	}
+}
+
+// writeLoop is the only writer of conn. Every write has a deadline, and a
+// ping goes out every pingPeriod so dead clients are noticed by the read
+// deadline.
+func (p *peerConn) writeLoop() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		_ = p.conn.Close()
+		p.finish()
+	}()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case msg := <-p.send:
+			_ = p.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if msg.close {
+				_ = p.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(msg.closeCode, msg.closeReason))
+				return
+			}
+			if err := p.conn.WriteMessage(websocket.TextMessage, msg.payload); err != nil {
+				if !isExpectedClose(err) {
+					log.Printf("signal: write error for %s: %v", p.user, err)
+				}
+				return
+			}
+		case <-ticker.C:
+			if err := p.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				return
+			}
+		}
+	}
 }
 
 func (p *peerConn) readLoop(hub *signalHub) {
+	var err error
 	defer func() {
 		_ = p.conn.Close()
-		hub.handlePeerLeave(p)
+		p.finish()
+		// Without a close frame from the client the connection was lost
+		// rather than left, and may be resumed.
+		hub.handlePeerLeave(p, websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway))
 	}()
 
 	for {
-		_, raw, err := p.conn.ReadMessage()
+		var raw []byte
+		_, raw, err = p.conn.ReadMessage()
 		if err != nil {
 			if !isExpectedClose(err) {
 				log.Printf("signal: read error for %s: %v", p.user, err)
@@ -84,24 +294,51 @@ func (p *peerConn) readLoop(hub *signalHub) {
 		}
 		if len(raw) > maxSignalPayloadSize {
 			log.Printf("signal: dropped oversized payload from %s (%d bytes)", p.user, len(raw))
+			_ = p.sendBytes(buildErrorPayload(p.currentRoom(), errCodeInvalidMessage, "message is too large"))
 			continue
 		}
 		hub.handleIncoming(p, raw)
 	}
 }
 
+// signalHub holds this node's connections. Deliveries and room-wide actions
+// go through the broker so they reach connections on every node; presence
+// (who is in a room) and the raised-hand queue are kept by the broker too.
+// Lobby connections are per node.
 type signalHub struct {
-	mu    sync.RWMutex
+	broker broker.Broker
+	// slowOps, when set, takes the envelope ops that call the broker or may
+	// wait on a connection; see runSlow.
+	slowOps chan func()
+
+	mu sync.RWMutex
+	// rooms maps each room to its connections on this node, by identity().
 	rooms map[string]map[string]*peerConn
+	// lobby holds connections of users waiting for admission, keyed by uid.
+	// They only receive lobby_status events until admitted.
+	lobby map[string]map[uint]*peerConn
+	// parked holds dropped connections waiting to be resumed, by token.
+	parked map[string]*peerConn
 }
 
-func newSignalHub() *signalHub {
+func newSignalHub(b broker.Broker) *signalHub {
 	return &signalHub{
-		rooms: make(map[string]map[string]*peerConn),
+		broker: b,
+		rooms:  make(map[string]map[string]*peerConn),
+		lobby:  make(map[string]map[uint]*peerConn),
+		parked: make(map[string]*peerConn),
 	}
 }
 
-var wsHub = newSignalHub()
+// newLocalSignalHub is a single-node hub on the in-memory broker.
+func newLocalSignalHub() *signalHub {
+	h := newSignalHub(broker.NewMemory())
+	_ = h.subscribe() // the in-memory broker cannot fail
+	return h
+}
+
+// wsHub is replaced by StartSignalBroker before the server starts.
+var wsHub = newLocalSignalHub()
 
 // SignalWebsocket godoc
 // @Summary WebRTC signaling websocket
@@ -110,6 +347,9 @@ var wsHub = newSignalHub()
 // @Param roomIdentity path string true "Room identity"
 // @Param userIdentity path string true "User identity"
 // @Param token query string true "JWT bearer token"
+// @Param protocol query int false "Signaling protocol version (1 or 2, default 1)"
+// @Param session query string false "Token of a dropped session to resume"
+// @Param device query string false "Device id, lets the user connect from several devices at once"
 // @Success 101 {string} string "Switching Protocols"
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
@@ -124,6 +364,32 @@ func SignalWebsocket(c *gin.Context) {
 		})
 		return
 	}
+	// '/' separates the device in connection identities, so a user identity
+	// containing one could pose as another user's device.
+	if strings.Contains(userIdentity, "/") {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  "userIdentity must not contain '/'",
+		})
+		return
+	}
+
+	protocol, err := parseProtocolVersion(c.Query("protocol"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  err.Error(),
+		})
+		return
+	}
+	device := c.Query("device")
+	if device != "" && !validDeviceID(device) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  "device must be 1-64 letters, digits, '-', '_' or '.'",
+		})
+		return
+	}
 
 	token := c.Query("token")
 	if token == "" {
@@ -134,7 +400,7 @@ func SignalWebsocket(c *gin.Context) {
 		return
 	}
 
-	claims, err := helper.AnalyzeToken(token)
+	claims, err := authenticateToken(token)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"code": http.StatusUnauthorized,
@@ -143,6 +409,14 @@ func SignalWebsocket(c *gin.Context) {
 		return
 	}
 
+	if !guestScopeAllows(claims, roomIdentity) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"code": http.StatusForbidden,
+			"msg":  "guest token is not valid for this room",
+		})
+		return
+	}
+
 	var room models.RoomBasic
 	if err := models.DB.Where("identify = ?", roomIdentity).First(&room).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
@@ -159,13 +433,41 @@ func SignalWebsocket(c *gin.Context) {
 		})
 		return
 	}
+	// Waiting in the lobby is allowed before the host starts the meeting;
+	// joining the room itself needs it live.
+	if err := ensureRoomNotClosed(&room); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  err.Error(),
+		})
+		return
+	}
 
 	var membership models.RoomUser
 	if err := models.DB.Where("rid = ? AND uid = ?", room.ID, claims.Id).First(&membership).Error; err != nil {
-		c.JSON(http.StatusForbidden, gin.H{
-			"code": http.StatusForbidden,
-			"msg":  "user is not a member of the room",
-		})
+		var entry models.RoomLobby
+		if err := models.DB.Where("rid = ? AND uid = ? AND status = ?", room.ID, claims.Id, models.LobbyStatusPending).
+			First(&entry).Error; err != nil {
+			c.JSON(http.StatusForbidden, gin.H{
+				"code": http.StatusForbidden,
+				"msg":  "user is not a member of the room",
+			})
+			return
+		}
+		if !signalIdentityMatches(userIdentity, claims, &models.RoomUser{DisplayName: entry.DisplayName}) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"code": http.StatusForbidden,
+				"msg":  "identity mismatch",
+			})
+			return
+		}
+		conn, err := wsUpgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			log.Printf("signal: websocket upgrade failed: %v", err)
+			return
+		}
+		configureWebsocketConn(conn)
+		handleLobbyConn(conn, roomIdentity, userIdentity, device, claims.Id, protocol)
 		return
 	}
 
@@ -176,6 +478,13 @@ func SignalWebsocket(c *gin.Context) {
 		})
 		return
 	}
+	if err := ensureMeetingLive(&room); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  err.Error(),
+		})
+		return
+	}
 
 	conn, err := wsUpgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
@@ -184,76 +493,271 @@ func SignalWebsocket(c *gin.Context) {
 	}
 
 	configureWebsocketConn(conn)
-	handleSignalConn(conn, roomIdentity, userIdentity)
+	handleSignalConn(conn, roomIdentity, userIdentity, device, claims.Id, protocol, c.Query("session"))
 }
 
-func handleSignalConn(conn *websocket.Conn, roomIdentity, userIdentity string) {
-	peer, existingPeers, err := wsHub.join(roomIdentity, userIdentity, conn)
+// handleSignalConn resumes the session of resumeToken when it is still
+// parked, and joins the room afresh otherwise.
+func handleSignalConn(conn *websocket.Conn, roomIdentity, userIdentity, device string, uid uint, protocol int, resumeToken string) {
+	if resumeToken != "" {
+		if peer, ok := wsHub.resumePeer(resumeToken, userIdentity, device, uid, protocol, conn); ok {
+			peer.serve(wsHub)
+			return
+		}
+	}
+	peer, existingPeers, err := wsHub.join(roomIdentity, userIdentity, device, uid, protocol, conn)
 	if err != nil {
-		payload := buildErrorPayload(roomIdentity, err.Error())
-		_ = conn.WriteMessage(websocket.TextMessage, payload)
-		_ = conn.Close()
+		rejectConn(conn, roomIdentity, err)
 		return
 	}
 
+	sendWelcome(peer)
+	sendSession(peer)
 	wsHub.sendPeerList(peer, existingPeers)
+	recordPeerJoined(roomIdentity, peer)
 	wsHub.notifyPeerJoined(peer)
-	peer.readLoop(wsHub)
+	peer.serve(wsHub)
 }
 
-func (h *signalHub) join(roomIdentity, userIdentity string, conn *websocket.Conn) (*peerConn, []string, error) {
-	if roomIdentity == "" || userIdentity == "" {
-		return nil, nil, errors.New("room or user identity is empty")
+// handleLobbyConn keeps a pending user's connection in the lobby until the
+// host admits (the peer is moved into the room) or denies it.
+func handleLobbyConn(conn *websocket.Conn, roomIdentity, userIdentity, device string, uid uint, protocol int) {
+	peer, err := wsHub.joinLobby(roomIdentity, userIdentity, device, uid, protocol, conn)
+	if err != nil {
+		rejectConn(conn, roomIdentity, err)
+		return
+	}
+
+	sendWelcome(peer)
+	sendLobbyStatus(peer, models.LobbyStatusPending, "")
+	peer.serve(wsHub)
+}
+
+// rejectConn reports why a connection could not join and closes it.
+func rejectConn(conn *websocket.Conn, roomIdentity string, err error) {
+	sigErr := &signalError{Code: errCodeInternal, Message: err.Error()}
+	errors.As(err, &sigErr)
+	_ = conn.WriteMessage(websocket.TextMessage, buildSignalError(roomIdentity, *sigErr))
+	_ = conn.Close()
+}
+
+// sendWelcome tells a new connection which protocol version is in use.
+func sendWelcome(peer *peerConn) {
+	if err := peer.sendBytes(welcomePayload(peer)); err != nil {
+		log.Printf("signal: send welcome error: %v", err)
 	}
+}
+
+func welcomePayload(peer *peerConn) []byte {
+	return buildSystemPayload(peer.currentRoom(), "welcome", map[string]interface{}{
+		"protocol":  peer.protocol,
+		"supported": supportedProtocols,
+	})
+}
 
+func (h *signalHub) joinLobby(roomIdentity, userIdentity, device string, uid uint, protocol int, conn *websocket.Conn) (*peerConn, error) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
+	waiting, ok := h.lobby[roomIdentity]
+	if !ok {
+		waiting = make(map[uint]*peerConn)
+		h.lobby[roomIdentity] = waiting
+	}
+	if _, exists := waiting[uid]; exists {
+		return nil, &signalError{Code: errCodeConflict, Message: fmt.Sprintf("user %s already waiting in room %s", userIdentity, roomIdentity)}
+	}
+
+	peer := newPeerConn(conn, roomIdentity, userIdentity, device, uid, protocol)
+	peer.inLobby = true
+	waiting[uid] = peer
+	return peer, nil
+}
+
+// admitFromLobby moves the user's waiting connection, on whichever node it
+// is, into the room. Without one they join normally on connect.
+func (h *signalHub) admitFromLobby(roomIdentity string, uid uint) {
+	h.publish(signalEnvelope{Op: opLobbyAdmit, Room: roomIdentity, UID: uid})
+}
+
+func (h *signalHub) admitLocal(roomIdentity string, uid uint) {
+	h.mu.RLock()
+	peer, ok := h.lobby[roomIdentity][uid]
+	h.mu.RUnlock()
+	if !ok {
+		return
+	}
+	claimed, err := h.claim(roomIdentity, peer)
+	if err != nil {
+		log.Printf("signal: admit %s to %s: %v", peer.user, roomIdentity, err)
+	}
+
+	h.mu.Lock()
+	if !h.removeLobbyPeerLocked(peer) {
+		// It disconnected while the claim was in flight.
+		h.mu.Unlock()
+		if claimed {
+			h.release(roomIdentity, peer)
+		}
+		return
+	}
 	roomPeers, ok := h.rooms[roomIdentity]
 	if !ok {
 		roomPeers = make(map[string]*peerConn)
 		h.rooms[roomIdentity] = roomPeers
 	}
+	if _, exists := roomPeers[peer.identity()]; exists || !claimed {
+		h.mu.Unlock()
+		sendLobbyStatus(peer, models.LobbyStatusAdmitted, "already connected")
+		peer.close(websocket.ClosePolicyViolation, "already connected")
+		return
+	}
+	peer.inLobby = false
+	roomPeers[peer.identity()] = peer
+	h.mu.Unlock()
+
+	sendLobbyStatus(peer, models.LobbyStatusAdmitted, "")
+	sendSession(peer)
+	h.sendPeerList(peer, h.otherMembers(roomIdentity, peer.identity()))
+	recordPeerJoined(roomIdentity, peer)
+	h.notifyPeerJoined(peer)
+}
+
+// denyFromLobby tells the user's waiting connection it was denied and
+// closes it.
+func (h *signalHub) denyFromLobby(roomIdentity string, uid uint, reason string) {
+	h.publish(signalEnvelope{Op: opLobbyDeny, Room: roomIdentity, UID: uid, Reason: reason})
+}
 
-	if _, exists := roomPeers[userIdentity]; exists {
-		return nil, nil, fmt.Errorf("user %s already connected in room %s", userIdentity, roomIdentity)
+func (h *signalHub) denyLocal(roomIdentity string, uid uint, reason string) {
+	h.mu.Lock()
+	peer, ok := h.lobby[roomIdentity][uid]
+	if ok {
+		h.removeLobbyPeerLocked(peer)
 	}
+	h.mu.Unlock()
+	if !ok {
+		return
+	}
+	sendLobbyStatus(peer, models.LobbyStatusDenied, reason)
+	peer.close(websocket.CloseNormalClosure, "denied")
+}
 
-	peer := &peerConn{
-		conn: conn,
-		room: roomIdentity,
-		user: userIdentity,
+func (h *signalHub) removeLobbyPeerLocked(peer *peerConn) bool {
+	waiting, ok := h.lobby[peer.currentRoom()]
+	if !ok || waiting[peer.uid] != peer {
+		return false
+	}
+	delete(waiting, peer.uid)
+	if len(waiting) == 0 {
+		delete(h.lobby, peer.currentRoom())
 	}
-	roomPeers[userIdentity] = peer
+	return true
+}
 
-	existing := make([]string, 0, len(roomPeers)-1)
-	for id := range roomPeers {
-		if id == userIdentity {
-			continue
-		}
-		existing = append(existing, id)
+func (h *signalHub) isInLobby(peer *peerConn) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return peer.inLobby
+}
+
+// sendToUser delivers payload to the user's connections in the room.
+func (h *signalHub) sendToUser(roomIdentity string, uid uint, payload []byte) {
+	h.publish(signalEnvelope{Op: opDeliver, Room: roomIdentity, Payload: payload, UID: uid})
+}
+
+func sendLobbyStatus(peer *peerConn, status, reason string) {
+	value := map[string]string{"status": status}
+	if reason != "" {
+		value["reason"] = reason
+	}
+	payload := buildSystemPayload(peer.currentRoom(), "lobby_status", value)
+	if err := peer.sendBytes(payload); err != nil {
+		log.Printf("signal: send lobby status error: %v", err)
 	}
+}
 
-	return peer, existing, nil
+// join claims the connection identity in the room cluster-wide and
+// registers the connection on this node. It returns the connections already
+// in the room.
+func (h *signalHub) join(roomIdentity, userIdentity, device string, uid uint, protocol int, conn *websocket.Conn) (*peerConn, []broker.Member, error) {
+	if roomIdentity == "" || userIdentity == "" {
+		return nil, nil, &signalError{Code: errCodeInvalidMessage, Message: "room or user identity is empty"}
+	}
+
+	peer := newPeerConn(conn, roomIdentity, userIdentity, device, uid, protocol)
+	identity := peer.identity()
+	h.expireParked(roomIdentity, identity)
+	claimed, err := h.claim(roomIdentity, peer)
+	if err == nil && !claimed {
+		claimed, err = h.takeOverParked(roomIdentity, peer)
+	}
+	if err != nil {
+		log.Printf("signal: claim %s in %s: %v", identity, roomIdentity, err)
+		return nil, nil, &signalError{Code: errCodeUnavailable, Message: "signaling is unavailable, try again"}
+	}
+	if !claimed {
+		return nil, nil, errAlreadyConnected(identity, roomIdentity)
+	}
+
+	h.mu.Lock()
+	roomPeers, ok := h.rooms[roomIdentity]
+	if !ok {
+		roomPeers = make(map[string]*peerConn)
+		h.rooms[roomIdentity] = roomPeers
+	}
+	if _, exists := roomPeers[identity]; exists {
+		h.mu.Unlock()
+		return nil, nil, errAlreadyConnected(identity, roomIdentity)
+	}
+	roomPeers[identity] = peer
+	h.mu.Unlock()
+
+	return peer, h.otherMembers(roomIdentity, identity), nil
 }
 
+// handleIncoming validates a client message against the sender's protocol
+// catalog, then runs its handler or forwards it.
 func (h *signalHub) handleIncoming(sender *peerConn, raw []byte) {
+	if h.isInLobby(sender) {
+		return
+	}
+	roomIdentity := sender.currentRoom()
 	var msg signalMessage
 	if err := json.Unmarshal(raw, &msg); err != nil {
-		log.Printf("signal: invalid json payload from %s: %v", sender.user, err)
+		_ = sender.sendBytes(buildErrorPayload(roomIdentity, errCodeInvalidMessage, "message is not valid JSON"))
 		return
 	}
-	if strings.TrimSpace(msg.Key) == "" {
+	spec, sigErr := checkClientMessage(sender.protocol, &msg)
+	if sigErr != nil {
+		_ = sender.sendBytes(buildSignalError(roomIdentity, *sigErr))
+		return
+	}
+	if spec.authorize != nil {
+		var room models.RoomBasic
+		if err := models.DB.Where("identify = ?", roomIdentity).First(&room).Error; err != nil {
+			_ = sender.sendBytes(buildErrorPayload(roomIdentity, errCodeNotFound, "room not found"))
+			return
+		}
+		if !spec.authorize(&room, sender, msg.Value) {
+			_ = sender.sendBytes(buildSignalError(roomIdentity, signalError{Code: errCodeForbidden, Message: "no permission", Key: msg.Key}))
+			return
+		}
+	}
+	if spec.handle != nil {
+		spec.handle(sender, &msg)
 		return
 	}
-
 	h.forward(sender, &msg)
 }
 
 func (h *signalHub) forward(sender *peerConn, msg *signalMessage) {
+	roomIdentity := sender.currentRoom()
 	msg.UserIdentity = sender.user
-	msg.RoomIdentity = sender.room
+	msg.PeerIdentity = sender.identity()
+	msg.RoomIdentity = roomIdentity
 	msg.Timestamp = time.Now().UnixMilli()
+	msg.System = false
 
 	payload, err := json.Marshal(msg)
 	if err != nil {
@@ -261,66 +765,50 @@ func (h *signalHub) forward(sender *peerConn, msg *signalMessage) {
 		return
 	}
 
-	targets := h.selectTargets(sender.room, sender.user, msg.TargetIdentity)
-	for _, peer := range targets {
-		if err := peer.sendBytes(payload); err != nil {
-			log.Printf("signal: send error to %s: %v", peer.user, err)
-		}
-	}
-}
-
-func (h *signalHub) selectTargets(roomIdentity, senderIdentity, targetIdentity string) []*peerConn {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
-
-	roomPeers, ok := h.rooms[roomIdentity]
-	if !ok {
-		return nil
-	}
-
-	if targetIdentity != "" {
-		if peer, ok := roomPeers[targetIdentity]; ok {
-			return []*peerConn{peer}
-		}
-		return nil
-	}
-
-	targets := make([]*peerConn, 0, len(roomPeers)-1)
-	for id, peer := range roomPeers {
-		if id == senderIdentity {
-			continue
-		}
-		targets = append(targets, peer)
+	env := signalEnvelope{Op: opDeliver, Room: roomIdentity, Payload: payload, Key: msg.Key}
+	if msg.TargetIdentity != "" {
+		env.Target = msg.TargetIdentity
+	} else {
+		env.Exclude = sender.identity()
 	}
-	return targets
+	h.publish(env)
 }
 
 func (h *signalHub) broadcast(roomIdentity string, payload []byte) {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
+	h.publish(signalEnvelope{Op: opDeliver, Room: roomIdentity, Payload: payload})
+}
 
-	roomPeers, ok := h.rooms[roomIdentity]
-	if !ok {
+// handlePeerLeave runs when a connection ends. A lost room connection is
+// parked for a resume; otherwise the peer leaves right away.
+func (h *signalHub) handlePeerLeave(peer *peerConn, closed bool) {
+	h.mu.Lock()
+	inLobby := peer.inLobby
+	if inLobby {
+		h.removeLobbyPeerLocked(peer)
+	}
+	h.mu.Unlock()
+	if inLobby {
 		return
 	}
-	for _, peer := range roomPeers {
-		if err := peer.sendBytes(payload); err != nil {
-			log.Printf("signal: broadcast error to %s: %v", peer.user, err)
-		}
+	if !closed && h.parkPeer(peer) {
+		return
 	}
-}
 
-func (h *signalHub) handlePeerLeave(peer *peerConn) {
-	targets, removed := h.removePeer(peer.room, peer.user)
+	roomIdentity, removed := h.removePeer(peer)
 	if !removed {
 		return
 	}
+	recordPeerLeft(roomIdentity, peer)
+	h.notifyPeerLeft(roomIdentity, peer)
+}
 
+func (h *signalHub) notifyPeerLeft(roomIdentity string, peer *peerConn) {
 	msg := signalMessage{
 		UserIdentity: peer.user,
-		RoomIdentity: peer.room,
+		PeerIdentity: peer.identity(),
+		RoomIdentity: roomIdentity,
 		Key:          "peer_left",
-		Value:        mustRawMessage(map[string]string{"user_identity": peer.user}),
+		Value:        mustRawMessage(peerValue(peer)),
 		System:       true,
 		Timestamp:    time.Now().UnixMilli(),
 	}
@@ -328,47 +816,250 @@ func (h *signalHub) handlePeerLeave(peer *peerConn) {
 	if err != nil {
 		return
 	}
+	h.broadcast(roomIdentity, payload)
+}
+
+// removePeer unregisters the connection from the room it is currently in.
+// The room is read under the hub lock so a concurrent move cannot leave the
+// connection registered elsewhere.
+func (h *signalHub) removePeer(peer *peerConn) (string, bool) {
+	h.mu.Lock()
+	roomIdentity := peer.currentRoom()
+	if h.rooms[roomIdentity][peer.identity()] != peer {
+		h.mu.Unlock()
+		return roomIdentity, false
+	}
+	h.detachPeerLocked(roomIdentity, peer)
+	h.mu.Unlock()
+
+	h.release(roomIdentity, peer)
+	return roomIdentity, true
+}
+
+// detachPeerLocked deletes the peer from the room on this node.
+func (h *signalHub) detachPeerLocked(roomIdentity string, peer *peerConn) {
+	roomPeers := h.rooms[roomIdentity]
+	delete(roomPeers, peer.identity())
+	if len(roomPeers) == 0 {
+		delete(h.rooms, roomIdentity)
+	}
+}
 
-	for _, target := range targets {
-		if err := target.sendBytes(payload); err != nil {
-			log.Printf("signal: notify leave error: %v", err)
+// snapshot returns the connection identities in each room on all nodes.
+func (h *signalHub) snapshot() map[string][]string {
+	ctx, cancel := brokerContext()
+	defer cancel()
+	members, err := h.broker.Rooms(ctx)
+	if err != nil {
+		log.Printf("signal: presence snapshot: %v", err)
+	}
+	rooms := make(map[string][]string, len(members))
+	for roomIdentity, list := range members {
+		users := make([]string, 0, len(list))
+		for _, m := range list {
+			users = append(users, m.Identity)
 		}
+		rooms[roomIdentity] = users
+	}
+	return rooms
+}
+
+func (h *signalHub) roomPeerCount(roomIdentity string) int {
+	return len(h.roomMembers(roomIdentity))
+}
+
+// closeRoom disconnects every peer of the room.
+func (h *signalHub) closeRoom(roomIdentity string, code int, reason string) {
+	h.publish(signalEnvelope{Op: opCloseRoom, Room: roomIdentity, Code: code, Reason: reason})
+}
+
+func (h *signalHub) closeLocalRoom(roomIdentity string, code int, reason string) {
+	h.mu.RLock()
+	peers := make([]*peerConn, 0, len(h.rooms[roomIdentity]))
+	for _, peer := range h.rooms[roomIdentity] {
+		peers = append(peers, peer)
+	}
+	h.mu.RUnlock()
+
+	for _, peer := range peers {
+		peer.close(code, reason)
 	}
 }
 
-func (h *signalHub) removePeer(roomIdentity, userIdentity string) ([]*peerConn, bool) {
+// disconnectUser closes every connection of the user in all rooms.
+func (h *signalHub) disconnectUser(uid uint, code int, reason string) {
+	h.publish(signalEnvelope{Op: opDisconnect, UID: uid, Code: code, Reason: reason})
+}
+
+// disconnectFromRoom closes the user's connections to one room, including a
+// connection still waiting in its lobby.
+func (h *signalHub) disconnectFromRoom(roomIdentity string, uid uint, code int, reason string) {
+	h.publish(signalEnvelope{Op: opDisconnect, Room: roomIdentity, UID: uid, Code: code, Reason: reason})
+}
+
+// disconnectLocal closes the user's connections on this node: in one room
+// and its lobby, or in every room when roomIdentity is empty.
+func (h *signalHub) disconnectLocal(roomIdentity string, uid uint, code int, reason string) {
 	h.mu.Lock()
-	defer h.mu.Unlock()
+	peers := make([]*peerConn, 0, 1)
+	for id, roomPeers := range h.rooms {
+		if roomIdentity != "" && id != roomIdentity {
+			continue
+		}
+		for _, peer := range roomPeers {
+			if peer.uid == uid {
+				peers = append(peers, peer)
+			}
+		}
+	}
+	if peer, ok := h.lobby[roomIdentity][uid]; roomIdentity != "" && ok {
+		h.removeLobbyPeerLocked(peer)
+		peers = append(peers, peer)
+	}
+	h.mu.Unlock()
 
-	roomPeers, ok := h.rooms[roomIdentity]
-	if !ok {
-		return nil, false
+	for _, peer := range peers {
+		peer.close(code, reason)
+	}
+}
+
+// sendToAllExcept delivers payload to every peer of the room but the user's.
+func (h *signalHub) sendToAllExcept(roomIdentity string, uid uint, payload []byte) {
+	h.publish(signalEnvelope{Op: opDeliver, Room: roomIdentity, Payload: payload, UID: uid, ExceptUID: true})
+}
+
+// moveUser moves the user's connections from any of the from rooms into
+// the to room without reconnecting. The old room sees peer_left, the new one
+// peer_joined, and the moved peer gets breakout_moved and a fresh peer_list.
+func (h *signalHub) moveUser(uid uint, from []string, to string) {
+	h.publish(signalEnvelope{Op: opMove, Room: to, UID: uid, From: from})
+}
+
+func (h *signalHub) moveLocal(uid uint, from []string, to string) {
+	type move struct {
+		peer *peerConn
+		from string
 	}
-	if _, exists := roomPeers[userIdentity]; !exists {
-		return nil, false
+	var moves []move
+	h.mu.RLock()
+	for _, roomIdentity := range from {
+		if roomIdentity == to {
+			continue
+		}
+		for _, peer := range h.rooms[roomIdentity] {
+			if peer.uid == uid {
+				moves = append(moves, move{peer: peer, from: roomIdentity})
+			}
+		}
 	}
+	h.mu.RUnlock()
 
-	delete(roomPeers, userIdentity)
+	for _, m := range moves {
+		claimed, err := h.claim(to, m.peer)
+		if err != nil {
+			log.Printf("signal: move %s to %s: %v", m.peer.user, to, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		h.mu.Lock()
+		if h.rooms[m.from][m.peer.identity()] != m.peer {
+			// It left while the claim was in flight.
+			h.mu.Unlock()
+			h.release(to, m.peer)
+			continue
+		}
+		h.detachPeerLocked(m.from, m.peer)
+		target, ok := h.rooms[to]
+		if !ok {
+			target = make(map[string]*peerConn)
+			h.rooms[to] = target
+		}
+		target[m.peer.identity()] = m.peer
+		m.peer.setRoom(to)
+		h.mu.Unlock()
+		h.release(m.from, m.peer)
 
-	targets := make([]*peerConn, 0, len(roomPeers))
-	for _, peer := range roomPeers {
-		targets = append(targets, peer)
+		recordPeerLeft(m.from, m.peer)
+		h.notifyPeerLeft(m.from, m.peer)
+		payload := buildSystemPayload(to, "breakout_moved", map[string]string{
+			"from":          m.from,
+			"room_identity": to,
+		})
+		if err := m.peer.sendBytes(payload); err != nil {
+			log.Printf("signal: send move error to %s: %v", m.peer.user, err)
+		}
+		h.sendPeerList(m.peer, h.otherMembers(to, m.peer.identity()))
+		recordPeerJoined(to, m.peer)
+		h.notifyPeerJoined(m.peer)
 	}
+}
 
-	if len(roomPeers) == 0 {
-		delete(h.rooms, roomIdentity)
+// peerDevice is one connection of a user in peer_list.
+type peerDevice struct {
+	PeerIdentity string `json:"peer_identity"`
+	Device       string `json:"device,omitempty"`
+}
+
+// peerUser groups a user's connections in peer_list.
+type peerUser struct {
+	UserIdentity string       `json:"user_identity"`
+	UserID       uint         `json:"user_id"`
+	Devices      []peerDevice `json:"devices"`
+}
+
+// groupPeersByUser turns room members into peer_list users, in the order
+// their first connection appears. Connections are grouped by account, since
+// one account may connect under several identities (id, username, display
+// name); a member without a UID stands alone.
+func groupPeersByUser(members []broker.Member) []peerUser {
+	type userKey struct {
+		uid  uint
+		user string
+	}
+	users := make([]peerUser, 0, len(members))
+	index := make(map[userKey]int, len(members))
+	for _, m := range members {
+		user := m.User
+		if user == "" {
+			user = m.Identity
+		}
+		key := userKey{uid: m.UID}
+		if m.UID == 0 {
+			key.user = m.Identity
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(users)
+			index[key] = i
+			users = append(users, peerUser{UserIdentity: user, UserID: m.UID})
+		}
+		users[i].Devices = append(users[i].Devices, peerDevice{PeerIdentity: m.Identity, Device: m.Device})
 	}
-	return targets, true
+	return users
 }
 
-func (h *signalHub) sendPeerList(peer *peerConn, peers []string) {
+// sendPeerList gives a joining peer the room snapshot: the connections on
+// any node, flat in peers and by user in users, and whose hand is up, in
+// raise order.
+func (h *signalHub) sendPeerList(peer *peerConn, members []broker.Member) {
+	roomIdentity := peer.currentRoom()
+	peers := make([]string, len(members))
+	for i, m := range members {
+		peers[i] = m.Identity
+	}
 	msg := signalMessage{
 		UserIdentity: "system",
-		RoomIdentity: peer.room,
+		RoomIdentity: roomIdentity,
 		Key:          "peer_list",
-		Value:        mustRawMessage(map[string][]string{"peers": peers}),
-		System:       true,
-		Timestamp:    time.Now().UnixMilli(),
+		Value: mustRawMessage(map[string]interface{}{
+			"peers":        peers,
+			"users":        groupPeersByUser(members),
+			"raised_hands": h.raisedHands(roomIdentity),
+		}),
+		System:    true,
+		Timestamp: time.Now().UnixMilli(),
 	}
 	payload, err := json.Marshal(msg)
 	if err != nil {
@@ -382,9 +1073,10 @@ func (h *signalHub) sendPeerList(peer *peerConn, peers []string) {
 func (h *signalHub) notifyPeerJoined(peer *peerConn) {
 	msg := signalMessage{
 		UserIdentity: peer.user,
-		RoomIdentity: peer.room,
+		PeerIdentity: peer.identity(),
+		RoomIdentity: peer.currentRoom(),
 		Key:          "peer_joined",
-		Value:        mustRawMessage(map[string]string{"user_identity": peer.user}),
+		Value:        mustRawMessage(peerValue(peer)),
 		System:       true,
 		Timestamp:    time.Now().UnixMilli(),
 	}
@@ -392,26 +1084,53 @@ func (h *signalHub) notifyPeerJoined(peer *peerConn) {
 	if err != nil {
 		return
 	}
-	targets := h.selectTargets(peer.room, peer.user, "")
-	for _, target := range targets {
-		if err := target.sendBytes(payload); err != nil {
-			log.Printf("signal: notify join error: %v", err)
-		}
+	h.publish(signalEnvelope{Op: opDeliver, Room: peer.currentRoom(), Payload: payload, Exclude: peer.identity()})
+}
+
+// peerValue describes the connection in peer_joined and peer_left.
+func peerValue(peer *peerConn) map[string]string {
+	value := map[string]string{
+		"user_identity": peer.user,
+		"peer_identity": peer.identity(),
+	}
+	if peer.device != "" {
+		value["device"] = peer.device
+	}
+	return value
+}
+
+func buildSystemPayload(roomIdentity, key string, value interface{}) []byte {
+	msg := signalMessage{
+		UserIdentity: "system",
+		RoomIdentity: roomIdentity,
+		Key:          key,
+		Value:        mustRawMessage(value),
+		System:       true,
+		Timestamp:    time.Now().UnixMilli(),
+	}
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return buildErrorPayload(roomIdentity, errCodeInternal, "internal error")
 	}
+	return payload
+}
+
+func buildErrorPayload(roomIdentity, code, message string) []byte {
+	return buildSignalError(roomIdentity, signalError{Code: code, Message: message})
 }
 
-func buildErrorPayload(roomIdentity, message string) []byte {
+func buildSignalError(roomIdentity string, sigErr signalError) []byte {
 	msg := signalMessage{
 		UserIdentity: "system",
 		RoomIdentity: roomIdentity,
 		Key:          "error",
-		Value:        mustRawMessage(map[string]string{"message": message}),
+		Value:        mustRawMessage(sigErr),
 		System:       true,
 		Timestamp:    time.Now().UnixMilli(),
 	}
 	payload, err := json.Marshal(msg)
 	if err != nil {
-		return []byte(`{"key":"error","value":{"message":"internal error"}}`)
+		return []byte(`{"key":"error","value":{"code":"internal","message":"internal error"}}`)
 	}
 	return payload
 }
@@ -451,9 +1170,24 @@ func configureWebsocketConn(conn *websocket.Conn) {
 	})
 }
 
+// validDeviceID accepts up to 64 letters, digits, '-', '_' and '.'.
+func validDeviceID(device string) bool {
+	if len(device) == 0 || len(device) > 64 {
+		return false
+	}
+	for _, r := range device {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 func signalIdentityMatches(identity string, claims *helper.UserClaims, membership *models.RoomUser) bool {
 	identity = strings.TrimSpace(identity)
-	if identity == "" {
+	if identity == "" || strings.Contains(identity, "/") {
 		return false
 	}
 	expectedID := strconv.FormatUint(uint64(claims.Id), 10)
@@ -469,7 +1203,33 @@ func signalIdentityMatches(identity string, claims *helper.UserClaims, membershi
 	return false
 }
 
+// screenShareWebhooks maps signaling events to the webhook events they raise.
+var screenShareWebhooks = map[string]string{
+	"screen_share_started": webhook.ScreenShareStarted,
+	"screen_share_stopped": webhook.ScreenShareStopped,
+}
+
 func notifyScreenShareEvent(roomIdentity, key string, value interface{}) {
+	notifyRoomEvent(roomIdentity, key, value)
+	if event, ok := screenShareWebhooks[key]; ok {
+		emitWebhookEvent(event, map[string]interface{}{
+			"room_identity": roomIdentity,
+			"share":         value,
+		})
+	}
+}
+
+func emitParticipantWebhook(event, roomIdentity string, peer *peerConn) {
+	emitWebhookEvent(event, map[string]interface{}{
+		"room_identity": roomIdentity,
+		"user_id":       peer.uid,
+		"user_identity": peer.user,
+		"peer_identity": peer.identity(),
+	})
+}
+
+// notifyRoomEvent broadcasts a system message to every peer of the room.
+func notifyRoomEvent(roomIdentity, key string, value interface{}) {
 	msg := signalMessage{
 		UserIdentity: "system",
 		RoomIdentity: roomIdentity,
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"GoMeetings/internal/broker"
+	"errors"
+	"testing"
+)
+
+func TestSendQueueDropsWhenFull(t *testing.T) {
+	p := &peerConn{
+		send:         make(chan outbound, 2),
+		done:         make(chan struct{}),
+		dropWhenFull: true,
+	}
+	for i := 0; i < 2; i++ {
+		if err := p.sendBytes([]byte("{}")); err != nil {
+			t.Fatalf("send %d: %v", i, err)
+		}
+	}
+	if err := p.sendBytes([]byte("{}")); !errors.Is(err, errSendQueueFull) {
+		t.Fatalf("full queue: err = %v", err)
+	}
+	if !p.dropping.Load() {
+		t.Fatal("peer should be marked as dropping")
+	}
+
+	<-p.send
+	if err := p.sendBytes([]byte("{}")); err != nil || p.dropping.Load() {
+		t.Fatalf("queue has room again: err = %v, dropping = %v", err, p.dropping.Load())
+	}
+
+	p.finish()
+	if err := p.sendBytes([]byte("{}")); !errors.Is(err, errPeerClosed) {
+		t.Fatalf("closed peer: err = %v", err)
+	}
+}
+
+func TestPeerListGroupsDevices(t *testing.T) {
+	users := groupPeersByUser([]broker.Member{
+		{Identity: "alice/laptop", User: "alice", Device: "laptop", UID: 1},
+		{Identity: "bob", User: "bob", UID: 2},
+		{Identity: "1/phone", User: "1", Device: "phone", UID: 1},
+	})
+	if len(users) != 2 || users[0].UserIdentity != "alice" || users[1].UserIdentity != "bob" {
+		t.Fatalf("users = %+v", users)
+	}
+	if d := users[0].Devices; len(d) != 2 || d[0].PeerIdentity != "alice/laptop" || d[1].PeerIdentity != "1/phone" {
+		t.Fatalf("alice's devices = %+v", d)
+	}
+}
+
+func TestHandStaysUpWhileAnotherDeviceIsConnected(t *testing.T) {
+	h := newLocalSignalHub()
+	laptop := &peerConn{room: "r1", user: "alice", device: "laptop", uid: 1}
+	// The same account, connected by its username and by its id.
+	phone := &peerConn{room: "r1", user: "1", device: "phone", uid: 1}
+	h.rooms["r1"] = map[string]*peerConn{laptop.identity(): laptop, phone.identity(): phone}
+	for _, p := range []*peerConn{laptop, phone} {
+		if ok, _ := h.claim("r1", p); !ok {
+			t.Fatalf("claim of %s failed", p.identity())
+		}
+	}
+
+	if _, ok := h.raiseHand(phone); !ok {
+		t.Fatal("hand should go up")
+	}
+	if _, ok := h.raiseHand(laptop); ok {
+		t.Fatal("a second device should not raise the hand again")
+	}
+	h.detachPeerLocked("r1", phone)
+	h.release("r1", phone)
+	if hands := h.raisedHands("r1"); len(hands) != 1 {
+		t.Fatalf("hand went down with one device left: %+v", hands)
+	}
+	h.detachPeerLocked("r1", laptop)
+	h.release("r1", laptop)
+	if hands := h.raisedHands("r1"); len(hands) != 0 {
+		t.Fatalf("hand stayed up after the last device left: %+v", hands)
+	}
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"GoMeetings/internal/define"
+	"GoMeetings/internal/helper"
+	"GoMeetings/internal/models"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errRefreshTokenInvalid = errors.New("refresh token is invalid")
+	errRefreshTokenExpired = errors.New("refresh token has expired")
+	errRefreshTokenReused  = errors.New("refresh token reuse detected, please login again")
+	errTokenRevoked        = errors.New("token has been revoked")
+)
+
+func accessTokenTTL() time.Duration {
+	return helper.DurationFromEnv("ACCESS_TOKEN_TTL", define.AccessTokenTTL)
+}
+
+func refreshTokenTTL() time.Duration {
+	return helper.DurationFromEnv("REFRESH_TOKEN_TTL", define.RefreshTokenTTL)
+}
+
+// authenticateToken parses an access token and rejects it when its token
+// family has been revoked.
+func authenticateToken(token string) (*helper.UserClaims, error) {
+	claims, err := helper.AnalyzeToken(token)
+	if err != nil {
+		return nil, err
+	}
+	revoked, err := models.TokenFamilyRevoked(claims.Family)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, errTokenRevoked
+	}
+	return claims, nil
+}
+
+// issueTokenPair starts a new token family for user and returns an access
+// token together with its first refresh token.
+func issueTokenPair(user *models.UserBasic) (*TokenPairReply, error) {
+	return issueTokenPairInFamily(models.DB, user, helper.GenerateUUID())
+}
+
+func issueTokenPairInFamily(tx *gorm.DB, user *models.UserBasic, family string) (*TokenPairReply, error) {
+	raw, hash, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	refresh := models.RefreshToken{
+		Uid:       user.ID,
+		FamilyID:  family,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(refreshTokenTTL()),
+	}
+	if err := tx.Create(&refresh).Error; err != nil {
+		return nil, err
+	}
+
+	claims := &helper.UserClaims{
+		Id:     user.ID,
+		Name:   user.Username,
+		Family: family,
+	}
+	if user.IsAdmin() {
+		claims.IsAdmin = 1
+	}
+	token, err := helper.GenerateToken(claims, accessTokenTTL())
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPairReply{
+		Token:            token,
+		ExpiresAt:        claims.ExpiresAt.UnixMilli(),
+		RefreshToken:     raw,
+		RefreshExpiresAt: refresh.ExpiresAt.UnixMilli(),
+	}, nil
+}
+
+// rotateRefreshToken exchanges a refresh token for a new pair in the same
+// family. Presenting a token that was already rotated revokes the family.
+func rotateRefreshToken(raw string) (*TokenPairReply, error) {
+	var reply *TokenPairReply
+	reused := false
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		var current models.RefreshToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", hashRefreshToken(raw)).First(&current).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errRefreshTokenInvalid
+			}
+			return err
+		}
+		if current.RevokedAt != nil {
+			return errTokenRevoked
+		}
+		if current.UsedAt != nil {
+			reused = true
+			return errRefreshTokenReused
+		}
+		if time.Now().After(current.ExpiresAt) {
+			return errRefreshTokenExpired
+		}
+
+		var user models.UserBasic
+		if err := tx.First(&user, current.Uid).Error; err != nil || user.Disabled {
+			return errRefreshTokenInvalid
+		}
+
+		now := time.Now()
+		if err := tx.Model(&current).Update("used_at", &now).Error; err != nil {
+			return err
+		}
+		pair, err := issueTokenPairInFamily(tx, &user, current.FamilyID)
+		if err != nil {
+			return err
+		}
+		reply = pair
+		return nil
+	})
+	if reused {
+		// The transaction rolled back, so revoke outside of it.
+		var current models.RefreshToken
+		if models.DB.Where("token_hash = ?", hashRefreshToken(raw)).First(&current).Error == nil {
+			_ = revokeTokenFamily(current.FamilyID)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+func revokeTokenFamily(family string) error {
+	now := time.Now()
+	return models.DB.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", family).
+		Update("revoked_at", &now).Error
+}
+
+func revokeUserTokens(uid uint) error {
+	now := time.Now()
+	return models.DB.Model(&models.RefreshToken{}).
+		Where("uid = ? AND revoked_at IS NULL", uid).
+		Update("revoked_at", &now).Error
+}
+
+func newRefreshToken() (raw string, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	raw = base64.RawURLEncoding.EncodeToString(buf)
+	return raw, hashRefreshToken(raw), nil
+}
+
+func hashRefreshToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// WellKnownJWKS godoc
+// @Summary JSON Web Key Set
+// @Description Public keys for verifying GoMeetings access tokens (asymmetric keys only)
+// @Tags Public
+// @Produce json
+// @Success 200 {object} helper.JSONWebKeySet
+// @Router /.well-known/jwks.json [get]
+func WellKnownJWKS(c *gin.Context) {
+	set, err := helper.JWKS()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, set)
+}
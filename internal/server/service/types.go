@@ -21,6 +21,21 @@ type UserRegisterRequest struct {
 	Password string `json:"password" form:"password" binding:"required"`
 }
 
+type UserRefreshRequest struct {
+	RefreshToken string `json:"refresh_token" form:"refresh_token" binding:"required"`
+}
+
+type UserLogoutRequest struct {
+	All bool `json:"all" form:"all"`
+}
+
+type TokenPairReply struct {
+	Token            string `json:"token"`
+	ExpiresAt        int64  `json:"expires_at"`
+	RefreshToken     string `json:"refresh_token"`
+	RefreshExpiresAt int64  `json:"refresh_expires_at"`
+}
+
 type MeetingEditRequest struct {
 	Identify string `json:"identity"` // Support lowercase "identity" from request
 	*MeetingCreateRequest
@@ -38,21 +53,25 @@ type MeetingListReply struct {
 }
 
 type RoomCreateRequest struct {
-	Name        string `json:"name" form:"name" binding:"required"`
-	BeginAt     int64  `json:"begin_at" form:"begin_at" binding:"required"`
-	EndAt       int64  `json:"end_at" form:"end_at" binding:"required"`
-	JoinCode    string `json:"join_code" form:"join_code" binding:"omitempty"`
-	ShortCode   string `json:"short_code" form:"short_code" binding:"omitempty"`
-	DisplayName string `json:"display_name" form:"display_name" binding:"omitempty"`
+	Name         string `json:"name" form:"name" binding:"required"`
+	BeginAt      int64  `json:"begin_at" form:"begin_at" binding:"required"`
+	EndAt        int64  `json:"end_at" form:"end_at" binding:"required"`
+	JoinCode     string `json:"join_code" form:"join_code" binding:"omitempty"`
+	ShortCode    string `json:"short_code" form:"short_code" binding:"omitempty"`
+	DisplayName  string `json:"display_name" form:"display_name" binding:"omitempty"`
+	LobbyEnabled bool   `json:"lobby_enabled" form:"lobby_enabled"`
+	RRule        string `json:"rrule" form:"rrule"`
 }
 
 type RoomEditRequest struct {
-	Identify  string `json:"identity" form:"identity" binding:"required"`
-	Name      string `json:"name" form:"name" binding:"required"`
-	BeginAt   int64  `json:"begin_at" form:"begin_at" binding:"required"`
-	EndAt     int64  `json:"end_at" form:"end_at" binding:"required"`
-	JoinCode  string `json:"join_code" form:"join_code" binding:"omitempty"`
-	ShortCode string `json:"short_code" form:"short_code" binding:"omitempty"`
+	Identify     string  `json:"identity" form:"identity" binding:"required"`
+	Name         string  `json:"name" form:"name" binding:"required"`
+	BeginAt      int64   `json:"begin_at" form:"begin_at" binding:"required"`
+	EndAt        int64   `json:"end_at" form:"end_at" binding:"required"`
+	JoinCode     string  `json:"join_code" form:"join_code" binding:"omitempty"`
+	ShortCode    string  `json:"short_code" form:"short_code" binding:"omitempty"`
+	LobbyEnabled *bool   `json:"lobby_enabled" form:"lobby_enabled"`
+	RRule        *string `json:"rrule" form:"rrule"`
 }
 
 type RoomListRequest struct {
@@ -60,6 +79,9 @@ type RoomListRequest struct {
 	Size     int    `form:"size"`
 	Keyword  string `form:"keyword"`
 	Identity string `form:"identity"`
+	From     int64  `form:"from"`
+	To       int64  `form:"to"`
+	Status   string `form:"status"`
 }
 
 type UserRoomListRequest struct {
@@ -75,6 +97,26 @@ type RoomJoinRequest struct {
 	JoinCode    string `json:"join_code" form:"join_code" binding:"required"`
 }
 
+type RoomJoinByCodeRequest struct {
+	ShortCode   string `json:"short_code" form:"short_code" binding:"required"`
+	DisplayName string `json:"display_name" form:"display_name" binding:"required"`
+	JoinCode    string `json:"join_code" form:"join_code" binding:"required"`
+}
+
+type RoomPublicInfo struct {
+	Identity         string    `json:"identity"`
+	Name             string    `json:"name"`
+	ShortCode        string    `json:"short_code"`
+	BeginAt          time.Time `json:"begin_at"`
+	EndAt            time.Time `json:"end_at"`
+	JoinCodeRequired bool      `json:"join_code_required"`
+	RRule            string    `json:"rrule,omitempty"`
+	LobbyEnabled     bool      `json:"lobby_enabled"`
+	Locked           bool      `json:"locked"`
+	Status           string    `json:"status"`
+	Open             bool      `json:"open"`
+}
+
 type RoomLeaveRequest struct {
 	Identity string `json:"identity" form:"identity" binding:"required"`
 }
@@ -98,13 +140,17 @@ type ScreenShareStatusReply struct {
 }
 
 type RoomListItem struct {
-	Identity string       `json:"identity"`
-	Name     string       `json:"name"`
-	BeginAt  time.Time    `json:"begin_at"`
-	EndAt    time.Time    `json:"end_at"`
-	CreateID uint         `json:"create_id"`
-	Joined   bool         `json:"joined"`
-	Members  []RoomMember `json:"members,omitempty"`
+	Identity string    `json:"identity"`
+	Name     string    `json:"name"`
+	BeginAt  time.Time `json:"begin_at"`
+	EndAt    time.Time `json:"end_at"`
+	CreateID uint      `json:"create_id"`
+	Joined   bool      `json:"joined"`
+	RRule    string    `json:"rrule,omitempty"`
+	Status   string    `json:"status"`
+	// OccurrenceID is set when the list is expanded with from/to.
+	OccurrenceID int64        `json:"occurrence_id,omitempty"`
+	Members      []RoomMember `json:"members,omitempty"`
 }
 
 type RoomListReply struct {
@@ -116,9 +162,337 @@ type RoomMember struct {
 	UserID      uint   `json:"user_id"`
 	DisplayName string `json:"display_name"`
 	JoinedAt    int64  `json:"joined_at"`
+	Guest       bool   `json:"guest"`
+	Role        string `json:"role"`
 }
 
 type RoomMembersReply struct {
 	Identity string       `json:"identity"`
 	Members  []RoomMember `json:"members"`
 }
+
+type AdminListRequest struct {
+	Page    int    `form:"page"`
+	Size    int    `form:"size"`
+	Keyword string `form:"keyword"`
+}
+
+type AdminUserItem struct {
+	ID        uint   `json:"id"`
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	Disabled  bool   `json:"disabled"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+type AdminUserListReply struct {
+	Total int64           `json:"total"`
+	List  []AdminUserItem `json:"list"`
+}
+
+type AdminUserDisableRequest struct {
+	UserID   uint `json:"user_id" form:"user_id" binding:"required"`
+	Disabled bool `json:"disabled" form:"disabled"`
+}
+
+type AdminRoomItem struct {
+	Identity  string    `json:"identity"`
+	Name      string    `json:"name"`
+	BeginAt   time.Time `json:"begin_at"`
+	EndAt     time.Time `json:"end_at"`
+	CreateID  uint      `json:"create_id"`
+	ShortCode string    `json:"short_code"`
+	LivePeers int       `json:"live_peers"`
+	CreatedAt int64     `json:"created_at"`
+}
+
+type AdminRoomListReply struct {
+	Total int64           `json:"total"`
+	List  []AdminRoomItem `json:"list"`
+}
+
+type AdminRoomEndRequest struct {
+	Identity string `json:"identity" form:"identity" binding:"required"`
+}
+
+type SignalRoomOccupancy struct {
+	Identity string   `json:"identity"`
+	Peers    []string `json:"peers"`
+}
+
+type SignalOccupancyReply struct {
+	TotalRooms int                   `json:"total_rooms"`
+	TotalPeers int                   `json:"total_peers"`
+	Rooms      []SignalRoomOccupancy `json:"rooms"`
+}
+
+type GuestJoinRequest struct {
+	Identity    string `json:"identity" form:"identity"`
+	ShortCode   string `json:"short_code" form:"short_code"`
+	JoinCode    string `json:"join_code" form:"join_code" binding:"required"`
+	DisplayName string `json:"display_name" form:"display_name" binding:"required"`
+}
+
+type GuestJoinReply struct {
+	Token        string `json:"token"`
+	ExpiresAt    int64  `json:"expires_at"`
+	UserID       uint   `json:"user_id"`
+	DisplayName  string `json:"display_name"`
+	RoomIdentity string `json:"room_identity"`
+	LobbyStatus  string `json:"lobby_status,omitempty"`
+}
+
+type LobbyDecisionRequest struct {
+	Identity string `json:"identity" form:"identity" binding:"required"`
+	UserID   uint   `json:"user_id" form:"user_id" binding:"required"`
+	Reason   string `json:"reason" form:"reason"`
+}
+
+type LobbyEntry struct {
+	UserID      uint   `json:"user_id"`
+	DisplayName string `json:"display_name"`
+	Guest       bool   `json:"guest"`
+	Status      string `json:"status"`
+	RequestedAt int64  `json:"requested_at"`
+}
+
+type RoomRoleRequest struct {
+	Identity string `json:"identity" form:"identity" binding:"required"`
+	UserID   uint   `json:"user_id" form:"user_id" binding:"required"`
+	Role     string `json:"role" form:"role" binding:"required"`
+}
+
+type ModerationRequest struct {
+	Identity string `json:"identity" form:"identity" binding:"required"`
+	UserID   uint   `json:"user_id" form:"user_id" binding:"required"`
+	Reason   string `json:"reason" form:"reason"`
+}
+
+type RoomMuteRequest struct {
+	Identity string `json:"identity" form:"identity" binding:"required"`
+	UserID   uint   `json:"user_id" form:"user_id"` // 0 mutes everyone
+}
+
+type RoomLockRequest struct {
+	Identity string `json:"identity" form:"identity" binding:"required"`
+	Locked   bool   `json:"locked" form:"locked"`
+}
+
+type RoomOccurrenceEditRequest struct {
+	Identity   string `json:"identity" form:"identity" binding:"required"`
+	Occurrence int64  `json:"occurrence" form:"occurrence" binding:"required"`
+	BeginAt    int64  `json:"begin_at" form:"begin_at" binding:"required"`
+	EndAt      int64  `json:"end_at" form:"end_at" binding:"required"`
+}
+
+type RoomOccurrenceCancelRequest struct {
+	Identity   string `json:"identity" form:"identity" binding:"required"`
+	Occurrence int64  `json:"occurrence" form:"occurrence" binding:"required"`
+}
+
+type RoomOccurrenceItem struct {
+	Identity     string    `json:"identity"`
+	OccurrenceID int64     `json:"occurrence_id"`
+	BeginAt      time.Time `json:"begin_at"`
+	EndAt        time.Time `json:"end_at"`
+	Overridden   bool      `json:"overridden"`
+	Cancelled    bool      `json:"cancelled"`
+}
+
+type WebhookCreateRequest struct {
+	URL         string `json:"url" form:"url" binding:"required"`
+	Events      string `json:"events" form:"events"`
+	Secret      string `json:"secret" form:"secret"`
+	Description string `json:"description" form:"description"`
+}
+
+type WebhookIDRequest struct {
+	ID uint `json:"id" form:"id" binding:"required"`
+}
+
+type WebhookDeliveryListRequest struct {
+	SubscriptionID uint   `form:"subscription_id"`
+	Status         string `form:"status"`
+	Event          string `form:"event"`
+	Page           int    `form:"page"`
+	Size           int    `form:"size"`
+}
+
+type WebhookDeliveryListReply struct {
+	Total int64                    `json:"total"`
+	List  []models.WebhookDelivery `json:"list"`
+}
+
+type ChatHistoryRequest struct {
+	Identity string `form:"identity" binding:"required"`
+	BeforeID uint   `form:"before_id"`
+	Limit    int    `form:"limit"`
+}
+
+type ChatHistoryReply struct {
+	List    []ChatMessageItem `json:"list"`
+	HasMore bool              `json:"has_more"`
+}
+
+type ChatEditRequest struct {
+	Identity  string `json:"identity" form:"identity" binding:"required"`
+	MessageID uint   `json:"message_id" form:"message_id" binding:"required"`
+	Text      string `json:"text" form:"text" binding:"required"`
+}
+
+type ChatDeleteRequest struct {
+	Identity  string `json:"identity" form:"identity" binding:"required"`
+	MessageID uint   `json:"message_id" form:"message_id" binding:"required"`
+}
+
+type ChatMessageItem struct {
+	ID                uint   `json:"id"`
+	ClientID          string `json:"client_id,omitempty"`
+	SenderID          uint   `json:"sender_id"`
+	SenderIdentity    string `json:"sender_identity"`
+	SenderName        string `json:"sender_name"`
+	RecipientID       uint   `json:"recipient_id,omitempty"`
+	RecipientIdentity string `json:"recipient_identity,omitempty"`
+	Private           bool   `json:"private"`
+	Text              string `json:"text"`
+	CreatedAt         int64  `json:"created_at"`
+	EditedAt          int64  `json:"edited_at,omitempty"`
+}
+
+type BreakoutCreateRequest struct {
+	Identity string   `json:"identity" form:"identity" binding:"required"`
+	Names    []string `json:"names" form:"names"`
+	Count    int      `json:"count" form:"count"`
+}
+
+type BreakoutAssignRequest struct {
+	Identity string `json:"identity" form:"identity" binding:"required"`
+	Breakout string `json:"breakout" form:"breakout" binding:"required"`
+	UserID   uint   `json:"user_id" form:"user_id" binding:"required"`
+}
+
+type BreakoutAutoAssignRequest struct {
+	Identity string `json:"identity" form:"identity" binding:"required"`
+}
+
+type BreakoutCountdownRequest struct {
+	Identity   string `json:"identity" form:"identity" binding:"required"`
+	Seconds    int    `json:"seconds" form:"seconds" binding:"required"`
+	Message    string `json:"message" form:"message"`
+	AutoReturn bool   `json:"auto_return" form:"auto_return"`
+}
+
+type BreakoutReturnRequest struct {
+	Identity string `json:"identity" form:"identity" binding:"required"`
+}
+
+type BreakoutVisitRequest struct {
+	Identity string `json:"identity" form:"identity" binding:"required"`
+	Breakout string `json:"breakout" form:"breakout" binding:"required"`
+}
+
+type BreakoutRoomItem struct {
+	Identity  string       `json:"identity"`
+	Name      string       `json:"name"`
+	Members   []RoomMember `json:"members"`
+	Connected int          `json:"connected"`
+}
+
+type PollCreateRequest struct {
+	Identity  string   `json:"identity" form:"identity" binding:"required"`
+	Question  string   `json:"question" form:"question" binding:"required"`
+	Options   []string `json:"options" form:"options" binding:"required"`
+	Multiple  bool     `json:"multiple" form:"multiple"`
+	Anonymous bool     `json:"anonymous" form:"anonymous"`
+}
+
+type PollVoteRequest struct {
+	Identity  string `json:"identity" form:"identity" binding:"required"`
+	PollID    uint   `json:"poll_id" form:"poll_id" binding:"required"`
+	OptionIDs []uint `json:"option_ids" form:"option_ids" binding:"required"`
+}
+
+type PollCloseRequest struct {
+	Identity string `json:"identity" form:"identity" binding:"required"`
+	PollID   uint   `json:"poll_id" form:"poll_id" binding:"required"`
+}
+
+type PollOptionItem struct {
+	ID     uint     `json:"id"`
+	Text   string   `json:"text"`
+	Votes  int      `json:"votes"`
+	Voters []string `json:"voters,omitempty"`
+}
+
+type PollItem struct {
+	ID          uint             `json:"id"`
+	Question    string           `json:"question"`
+	Multiple    bool             `json:"multiple"`
+	Anonymous   bool             `json:"anonymous"`
+	Closed      bool             `json:"closed"`
+	ClosedAt    int64            `json:"closed_at,omitempty"`
+	CreatedAt   int64            `json:"created_at"`
+	Options     []PollOptionItem `json:"options"`
+	TotalVoters int              `json:"total_voters"`
+	MyVotes     []uint           `json:"my_votes,omitempty"`
+}
+
+type QuestionAskRequest struct {
+	Identity string `json:"identity" form:"identity" binding:"required"`
+	Text     string `json:"text" form:"text" binding:"required"`
+}
+
+type QuestionActionRequest struct {
+	Identity   string `json:"identity" form:"identity" binding:"required"`
+	QuestionID uint   `json:"question_id" form:"question_id" binding:"required"`
+}
+
+type QuestionItem struct {
+	ID         uint   `json:"id"`
+	AskerID    uint   `json:"asker_id"`
+	AskerName  string `json:"asker_name"`
+	Text       string `json:"text"`
+	Upvotes    int    `json:"upvotes"`
+	Upvoted    bool   `json:"upvoted"`
+	Answered   bool   `json:"answered"`
+	AnsweredAt int64  `json:"answered_at,omitempty"`
+	CreatedAt  int64  `json:"created_at"`
+}
+
+type EngagementExportReply struct {
+	Identity  string         `json:"identity"`
+	Name      string         `json:"name"`
+	Polls     []PollItem     `json:"polls"`
+	Questions []QuestionItem `json:"questions"`
+}
+
+type HandLowerRequest struct {
+	Identity     string `json:"identity" form:"identity" binding:"required"`
+	UserIdentity string `json:"user_identity" form:"user_identity"`
+}
+
+type AttendanceItem struct {
+	UserID       uint   `json:"user_id"`
+	DisplayName  string `json:"display_name"`
+	Guest        bool   `json:"guest"`
+	FirstJoin    int64  `json:"first_join"`
+	LastLeave    int64  `json:"last_leave,omitempty"`
+	TotalSeconds int64  `json:"total_seconds"`
+	Sessions     int    `json:"sessions"`
+	Reconnects   int    `json:"reconnects"`
+	Connected    bool   `json:"connected"`
+}
+
+type RoomExtendRequest struct {
+	Identity string `json:"identity" form:"identity" binding:"required"`
+	Minutes  int    `json:"minutes" form:"minutes" binding:"required"`
+}
+
+type RoomStatusRequest struct {
+	Identity string `json:"identity" form:"identity" binding:"required"`
+}
+
+type RoomCancelRequest struct {
+	Identity string `json:"identity" form:"identity" binding:"required"`
+	Reason   string `json:"reason" form:"reason"`
+}
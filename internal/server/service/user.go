@@ -4,6 +4,7 @@ import (
 	"GoMeetings/internal/helper"
 	"GoMeetings/internal/models"
 	"errors"
+	"log"
 	"net/http"
 	"strings"
 
@@ -39,10 +40,8 @@ func UserLogin(c *gin.Context) {
 		})
 		return
 	}
-	password := helper.GetMd5(in.Password)
-
 	data := new(models.UserBasic)
-	err = models.DB.Where("username = ? and password = ?", in.Username, password).First(data).Error
+	err = models.DB.Where("username = ?", in.Username).First(data).Error
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"code": -1,
@@ -50,8 +49,30 @@ func UserLogin(c *gin.Context) {
 		})
 		return
 	}
+	ok, needsRehash := helper.CheckPassword(data.Password, in.Password)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "username or password error",
+		})
+		return
+	}
+	if data.Disabled {
+		c.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "account is disabled",
+		})
+		return
+	}
+	if needsRehash {
+		if hash, err := helper.HashPassword(in.Password); err == nil {
+			if err := models.DB.Model(data).Update("password", hash).Error; err != nil {
+				log.Printf("user: password rehash failed for %d: %v", data.ID, err)
+			}
+		}
+	}
 
-	token, err := helper.GenerateToken(data.ID, data.Username)
+	pair, err := issueTokenPair(data)
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"code": -1,
@@ -61,10 +82,7 @@ func UserLogin(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, gin.H{
 		"code": 200,
-		"data": map[string]interface{}{
-			"token": token,
-			"user":  data,
-		},
+		"data": tokenPairData(pair, data),
 	})
 }
 
@@ -99,6 +117,10 @@ func UserRegister(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "password must be at least 6 characters"})
 		return
 	}
+	if len(password) > helper.MaxPasswordLength {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": helper.ErrPasswordTooLong.Error()})
+		return
+	}
 
 	var existing models.UserBasic
 	if err := models.DB.Where("username = ?", username).First(&existing).Error; err == nil {
@@ -109,16 +131,22 @@ func UserRegister(c *gin.Context) {
 		return
 	}
 
+	hash, err := helper.HashPassword(password)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
 	user := models.UserBasic{
 		Username: username,
-		Password: helper.GetMd5(password),
+		Password: hash,
+		Role:     models.UserRoleUser,
 	}
 	if err := models.DB.Create(&user).Error; err != nil {
 		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
 		return
 	}
 
-	token, err := helper.GenerateToken(user.ID, user.Username)
+	pair, err := issueTokenPair(&user)
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "Generate Token Error" + err.Error()})
 		return
@@ -126,9 +154,74 @@ func UserRegister(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"code": 200,
-		"data": map[string]interface{}{
-			"token": token,
-			"user":  user,
-		},
+		"data": tokenPairData(pair, &user),
 	})
 }
+
+// UserRefresh godoc
+// @Summary Refresh access token
+// @Description Rotate a refresh token and return a new access/refresh token pair
+// @Tags Auth
+// @Accept multipart/form-data
+// @Produce json
+// @Param refresh_token formData string true "Refresh token"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /auth/user/refresh [post]
+func UserRefresh(c *gin.Context) {
+	var req UserRefreshRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+
+	pair, err := rotateRefreshToken(strings.TrimSpace(req.RefreshToken))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": http.StatusUnauthorized, "msg": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": pair})
+}
+
+// UserLogout godoc
+// @Summary User logout
+// @Description Revoke the current token family, or every session of the user when all is true
+// @Tags Auth
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param all formData boolean false "Revoke all sessions"
+// @Success 200 {object} map[string]string
+// @Router /auth/user/logout [post]
+func UserLogout(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	var req UserLogoutRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+
+	var err error
+	if req.All {
+		err = revokeUserTokens(uc.Id)
+	} else {
+		err = revokeTokenFamily(uc.Family)
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "logout success"})
+}
+
+func tokenPairData(pair *TokenPairReply, user *models.UserBasic) map[string]interface{} {
+	return map[string]interface{}{
+		"token":              pair.Token,
+		"expires_at":         pair.ExpiresAt,
+		"refresh_token":      pair.RefreshToken,
+		"refresh_expires_at": pair.RefreshExpiresAt,
+		"user":               user,
+	}
+}
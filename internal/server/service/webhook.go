@@ -0,0 +1,416 @@
+package service
+
+import (
+	"GoMeetings/internal/define"
+	"GoMeetings/internal/helper"
+	"GoMeetings/internal/models"
+	"GoMeetings/internal/webhook"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// webhookWake nudges the worker when new deliveries are queued so they do
+// not wait for the next poll.
+var webhookWake = make(chan struct{}, 1)
+
+var webhookClient = &http.Client{Timeout: define.WebhookTimeout}
+
+// webhookEnvelope is the JSON body of every delivery.
+type webhookEnvelope struct {
+	ID        string      `json:"id"`
+	Event     string      `json:"event"`
+	CreatedAt int64       `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// emitWebhookEvent queues event for every active subscription that wants it.
+// Failures are logged; webhooks never fail the request that caused them.
+func emitWebhookEvent(event string, data interface{}) {
+	var subs []models.WebhookSubscription
+	if err := models.DB.Where("active = ?", true).Find(&subs).Error; err != nil {
+		log.Printf("webhook: load subscriptions: %v", err)
+		return
+	}
+	envelope := webhookEnvelope{
+		ID:        helper.GenerateUUID(),
+		Event:     event,
+		CreatedAt: time.Now().UnixMilli(),
+		Data:      data,
+	}
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		log.Printf("webhook: marshal %s: %v", event, err)
+		return
+	}
+
+	queued := false
+	for _, sub := range subs {
+		if !webhook.Matches(sub.Events, event) {
+			continue
+		}
+		delivery := models.WebhookDelivery{
+			SubscriptionID: sub.ID,
+			EventID:        envelope.ID,
+			Event:          event,
+			Payload:        string(payload),
+			Status:         models.WebhookDeliveryPending,
+			NextAttemptAt:  time.Now(),
+		}
+		if err := models.DB.Create(&delivery).Error; err != nil {
+			log.Printf("webhook: queue %s for subscription %d: %v", event, sub.ID, err)
+			continue
+		}
+		queued = true
+	}
+	if queued {
+		select {
+		case webhookWake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// StartWebhookWorker delivers queued webhooks until ctx is done.
+func StartWebhookWorker(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(define.WebhookPollInterval)
+		defer ticker.Stop()
+		for {
+			deliverDueWebhooks(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-webhookWake:
+			}
+		}
+	}()
+}
+
+func deliverDueWebhooks(ctx context.Context) {
+	for ctx.Err() == nil {
+		var due []models.WebhookDelivery
+		if err := models.DB.Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, time.Now()).
+			Order("next_attempt_at asc").Limit(20).Find(&due).Error; err != nil {
+			log.Printf("webhook: load due deliveries: %v", err)
+			return
+		}
+		if len(due) == 0 {
+			return
+		}
+		for i := range due {
+			if claimWebhookDelivery(&due[i]) {
+				attemptWebhookDelivery(ctx, &due[i])
+			}
+		}
+	}
+}
+
+// claimWebhookDelivery leases the row by pushing its next attempt past the
+// request timeout, so another instance polling the same table skips it.
+func claimWebhookDelivery(d *models.WebhookDelivery) bool {
+	lease := time.Now().Add(2 * define.WebhookTimeout)
+	res := models.DB.Model(&models.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at = ?", d.ID, models.WebhookDeliveryPending, d.NextAttemptAt).
+		Update("next_attempt_at", lease)
+	if res.Error != nil || res.RowsAffected == 0 {
+		return false
+	}
+	d.NextAttemptAt = lease
+	return true
+}
+
+func attemptWebhookDelivery(ctx context.Context, d *models.WebhookDelivery) {
+	var sub models.WebhookSubscription
+	if err := models.DB.First(&sub, d.SubscriptionID).Error; err != nil || !sub.Active {
+		finishWebhookDelivery(d, models.WebhookDeliveryFailed, 0, "subscription removed or inactive")
+		return
+	}
+
+	code, err := postWebhook(ctx, &sub, d)
+	d.Attempts++
+	if err == nil {
+		finishWebhookDelivery(d, models.WebhookDeliverySucceeded, code, "")
+		return
+	}
+	if d.Attempts >= define.WebhookMaxAttempts {
+		finishWebhookDelivery(d, models.WebhookDeliveryFailed, code, err.Error())
+		return
+	}
+	next := time.Now().Add(webhook.Backoff(d.Attempts))
+	if err := models.DB.Model(d).Updates(map[string]interface{}{
+		"attempts":         d.Attempts,
+		"next_attempt_at":  next,
+		"last_status_code": code,
+		"last_error":       truncate(err.Error(), 512),
+	}).Error; err != nil {
+		log.Printf("webhook: reschedule delivery %d: %v", d.ID, err)
+	}
+}
+
+func postWebhook(ctx context.Context, sub *models.WebhookSubscription, d *models.WebhookDelivery) (int, error) {
+	body := []byte(d.Payload)
+	ts := time.Now().Unix()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "GoMeetings-Webhook/1.0")
+	req.Header.Set(webhook.HeaderEvent, d.Event)
+	req.Header.Set(webhook.HeaderDelivery, d.EventID)
+	req.Header.Set(webhook.HeaderTimestamp, fmt.Sprint(ts))
+	req.Header.Set(webhook.HeaderSignature, webhook.Sign(sub.Secret, ts, body))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+func finishWebhookDelivery(d *models.WebhookDelivery, status string, code int, lastError string) {
+	update := map[string]interface{}{
+		"status":           status,
+		"attempts":         d.Attempts,
+		"last_status_code": code,
+		"last_error":       truncate(lastError, 512),
+	}
+	if status == models.WebhookDeliverySucceeded {
+		update["delivered_at"] = time.Now()
+	}
+	if err := models.DB.Model(d).Updates(update).Error; err != nil {
+		log.Printf("webhook: finish delivery %d: %v", d.ID, err)
+	}
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
+
+// roomWebhookData is the room object carried by room.* and meeting.* events.
+func roomWebhookData(room *models.RoomBasic) map[string]interface{} {
+	return map[string]interface{}{
+		"identity":   room.Identify,
+		"name":       room.Name,
+		"begin_at":   room.BeginAt.UnixMilli(),
+		"end_at":     room.EndAt.UnixMilli(),
+		"create_id":  room.CreateID,
+		"short_code": room.ShortCode,
+		"rrule":      room.RRule,
+		"status":     roomStatus(room),
+	}
+}
+
+// AdminWebhookList godoc
+// @Summary Admin: list webhook subscriptions
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/webhooks [get]
+func AdminWebhookList(c *gin.Context) {
+	var subs []models.WebhookSubscription
+	if err := models.DB.Order("id asc").Find(&subs).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": subs})
+}
+
+// AdminWebhookCreate godoc
+// @Summary Admin: create a webhook subscription
+// @Description The signing secret is only returned here. Deliveries carry X-GoMeetings-Signature: sha256=HMAC(secret, timestamp + "." + body).
+// @Tags Admin
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param url formData string true "Endpoint URL (http or https)"
+// @Param events formData string false "Comma-separated events, * or empty for all"
+// @Param secret formData string false "Signing secret, generated when empty"
+// @Param description formData string false "Description"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/webhooks [post]
+func AdminWebhookCreate(c *gin.Context) {
+	uc := c.MustGet("user_claims").(*helper.UserClaims)
+	req := WebhookCreateRequest{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	target, err := url.Parse(strings.TrimSpace(req.URL))
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "url must be an absolute http(s) URL"})
+		return
+	}
+	events, unknown := webhook.ParseEvents(req.Events)
+	if len(unknown) > 0 {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "unknown events: " + strings.Join(unknown, ", ")})
+		return
+	}
+	secret := req.Secret
+	if secret == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+			return
+		}
+		secret = hex.EncodeToString(buf)
+	}
+
+	sub := models.WebhookSubscription{
+		URL:         target.String(),
+		Secret:      secret,
+		Events:      events,
+		Description: req.Description,
+		Active:      true,
+		CreateID:    uc.Id,
+	}
+	if err := models.DB.Create(&sub).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": gin.H{"subscription": sub, "secret": secret}})
+}
+
+// AdminWebhookDelete godoc
+// @Summary Admin: delete a webhook subscription
+// @Description Pending deliveries of the subscription are dropped
+// @Tags Admin
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param id formData integer true "Subscription ID"
+// @Success 200 {object} map[string]string
+// @Router /admin/webhooks/delete [post]
+func AdminWebhookDelete(c *gin.Context) {
+	req := WebhookIDRequest{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	res := models.DB.Delete(&models.WebhookSubscription{}, req.ID)
+	if res.Error != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + res.Error.Error()})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "subscription not found"})
+		return
+	}
+	models.DB.Model(&models.WebhookDelivery{}).
+		Where("subscription_id = ? AND status = ?", req.ID, models.WebhookDeliveryPending).
+		Updates(map[string]interface{}{"status": models.WebhookDeliveryFailed, "last_error": "subscription deleted"})
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "subscription deleted"})
+}
+
+// AdminWebhookDeliveries godoc
+// @Summary Admin: webhook delivery log
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param subscription_id query int false "Subscription ID"
+// @Param status query string false "pending, succeeded or failed"
+// @Param event query string false "Event name"
+// @Param page query int false "Page number"
+// @Param size query int false "Page size"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/webhooks/deliveries [get]
+func AdminWebhookDeliveries(c *gin.Context) {
+	req := WebhookDeliveryListRequest{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.Size <= 0 {
+		req.Size = 20
+	}
+
+	query := models.DB.Model(&models.WebhookDelivery{})
+	if req.SubscriptionID != 0 {
+		query = query.Where("subscription_id = ?", req.SubscriptionID)
+	}
+	if req.Status != "" {
+		query = query.Where("status = ?", req.Status)
+	}
+	if req.Event != "" {
+		query = query.Where("event = ?", req.Event)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	var list []models.WebhookDelivery
+	if err := query.Order("id desc").
+		Limit(req.Size).Offset((req.Page - 1) * req.Size).
+		Find(&list).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": WebhookDeliveryListReply{Total: total, List: list}})
+}
+
+// AdminWebhookRetry godoc
+// @Summary Admin: retry a webhook delivery now
+// @Description Requeues a failed delivery, or a pending one that is due and not being attempted, with a fresh attempt budget
+// @Tags Admin
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param id formData integer true "Delivery ID"
+// @Success 200 {object} map[string]string
+// @Router /admin/webhooks/deliveries/retry [post]
+func AdminWebhookRetry(c *gin.Context) {
+	req := WebhookIDRequest{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "params error: " + err.Error()})
+		return
+	}
+	// A pending row whose next attempt lies ahead is leased by a worker
+	// (see claimWebhookDelivery) or waiting out its backoff; requeueing it
+	// could post it twice.
+	now := time.Now()
+	res := models.DB.Model(&models.WebhookDelivery{}).
+		Where("id = ? AND (status = ? OR (status = ? AND next_attempt_at <= ?))",
+			req.ID, models.WebhookDeliveryFailed, models.WebhookDeliveryPending, now).
+		Updates(map[string]interface{}{
+			"status":          models.WebhookDeliveryPending,
+			"attempts":        0,
+			"next_attempt_at": now,
+		})
+	if res.Error != nil {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "system error: " + res.Error.Error()})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "delivery not found, already delivered or being attempted"})
+		return
+	}
+	select {
+	case webhookWake <- struct{}{}:
+	default:
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "delivery requeued"})
+}
@@ -0,0 +1,171 @@
+package sso
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mockIssuer is a minimal OIDC provider: discovery, JWKS, authorize and a
+// token endpoint that enforces PKCE.
+type mockIssuer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]pendingCode
+}
+
+type pendingCode struct {
+	challenge string
+	nonce     string
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockIssuer{key: key, codes: make(map[string]pendingCode)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		base := m.server.URL
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                base,
+			"authorization_endpoint":                base + "/authorize",
+			"token_endpoint":                        base + "/token",
+			"jwks_uri":                              base + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "mock",
+				"alg": "RS256",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("code_challenge_method") != "S256" {
+			http.Error(w, "pkce required", http.StatusBadRequest)
+			return
+		}
+		m.mu.Lock()
+		m.codes["code-1"] = pendingCode{challenge: q.Get("code_challenge"), nonce: q.Get("nonce")}
+		m.mu.Unlock()
+		target, _ := url.Parse(q.Get("redirect_uri"))
+		values := target.Query()
+		values.Set("code", "code-1")
+		values.Set("state", q.Get("state"))
+		target.RawQuery = values.Encode()
+		http.Redirect(w, r, target.String(), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		m.mu.Lock()
+		pending, ok := m.codes[r.PostForm.Get("code")]
+		delete(m.codes, r.PostForm.Get("code"))
+		m.mu.Unlock()
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != pending.challenge {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss":            m.server.URL,
+			"sub":            "user-42",
+			"aud":            "gomeetings",
+			"exp":            time.Now().Add(time.Minute).Unix(),
+			"iat":            time.Now().Unix(),
+			"nonce":          pending.nonce,
+			"email":          "alice@example.com",
+			"email_verified": true,
+		})
+		idToken.Header["kid"] = "mock"
+		signed, err := idToken.SignedString(key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "at",
+			"token_type":   "Bearer",
+			"expires_in":   60,
+			"id_token":     signed,
+		})
+	})
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+func TestAuthorizationCodeFlowWithPKCE(t *testing.T) {
+	issuer := newMockIssuer(t)
+	ctx := context.Background()
+	provider, err := NewProvider(ctx, Config{
+		Issuer:      issuer.server.URL,
+		ClientID:    "gomeetings",
+		RedirectURL: "http://localhost:8080/auth/oidc/callback",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifier := NewVerifier()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(provider.AuthCodeURL("state-1", "nonce-1", verifier))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if callback.Query().Get("state") != "state-1" {
+		t.Fatalf("state not echoed: %s", callback)
+	}
+
+	if _, err := provider.Exchange(ctx, callback.Query().Get("code"), NewVerifier(), "nonce-1"); err == nil {
+		t.Fatal("exchange with the wrong verifier succeeded")
+	}
+
+	resp, err = client.Get(provider.AuthCodeURL("state-1", "nonce-1", verifier))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	identity, err := provider.Exchange(ctx, "code-1", verifier, "nonce-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.Issuer != issuer.server.URL || identity.Subject != "user-42" {
+		t.Fatalf("unexpected identity: %+v", identity)
+	}
+	if identity.Email != "alice@example.com" || !identity.EmailVerified {
+		t.Fatalf("unexpected email claims: %+v", identity)
+	}
+}
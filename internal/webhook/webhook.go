@@ -0,0 +1,119 @@
+// Package webhook holds the wire format of outbound webhooks: event names,
+// request signing and the retry schedule.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	RoomCreated        = "room.created"
+	RoomUpdated        = "room.updated"
+	RoomDeleted        = "room.deleted"
+	ParticipantJoined  = "participant.joined"
+	ParticipantLeft    = "participant.left"
+	ScreenShareStarted = "screen_share.started"
+	ScreenShareStopped = "screen_share.stopped"
+	MeetingStarted     = "meeting.started"
+	MeetingEnded       = "meeting.ended"
+	MeetingCancelled   = "meeting.cancelled"
+)
+
+// Events lists every event a subscription can ask for.
+var Events = []string{
+	RoomCreated, RoomUpdated, RoomDeleted,
+	ParticipantJoined, ParticipantLeft,
+	ScreenShareStarted, ScreenShareStopped,
+	MeetingStarted, MeetingEnded, MeetingCancelled,
+}
+
+// Request headers of a delivery.
+const (
+	HeaderEvent     = "X-GoMeetings-Event"
+	HeaderDelivery  = "X-GoMeetings-Delivery"
+	HeaderTimestamp = "X-GoMeetings-Timestamp"
+	HeaderSignature = "X-GoMeetings-Signature"
+)
+
+const (
+	baseBackoff = 30 * time.Second
+	maxBackoff  = 6 * time.Hour
+)
+
+// Sign returns the signature header value for body sent at timestamp (unix
+// seconds): "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body)).
+// Including the timestamp lets receivers reject replays.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature produced by Sign in constant time.
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// Backoff is the delay before retry number attempt (1-based): 30s doubling
+// up to 6h.
+func Backoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	d := baseBackoff
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if d >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return d
+}
+
+// ParseEvents normalises a comma-separated event filter. "*" or an empty
+// filter subscribes to everything; unknown names are reported.
+func ParseEvents(filter string) (string, []string) {
+	filter = strings.TrimSpace(filter)
+	if filter == "" || filter == "*" {
+		return "*", nil
+	}
+	known := make(map[string]bool, len(Events))
+	for _, e := range Events {
+		known[e] = true
+	}
+	var events, unknown []string
+	seen := make(map[string]bool)
+	for _, e := range strings.Split(filter, ",") {
+		e = strings.ToLower(strings.TrimSpace(e))
+		if e == "" || seen[e] {
+			continue
+		}
+		seen[e] = true
+		if !known[e] {
+			unknown = append(unknown, e)
+			continue
+		}
+		events = append(events, e)
+	}
+	return strings.Join(events, ","), unknown
+}
+
+// Matches reports whether a normalised filter includes event.
+func Matches(filter, event string) bool {
+	if filter == "*" {
+		return true
+	}
+	for _, e := range strings.Split(filter, ",") {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
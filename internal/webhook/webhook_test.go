@@ -0,0 +1,54 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	body := []byte(`{"event":"room.created"}`)
+	sig := Sign("secret", 1760000000, body)
+	// echo -n '1760000000.{"event":"room.created"}' | openssl dgst -sha256 -hmac secret
+	if sig != "sha256=711e652eb93f07a1f225c75eab3bba33c23854739c60c806ba424536f9bb93d2" {
+		t.Fatalf("Sign = %s", sig)
+	}
+	if !Verify("secret", 1760000000, body, sig) {
+		t.Fatal("signature does not verify")
+	}
+	if Verify("other", 1760000000, body, sig) {
+		t.Fatal("verified with the wrong secret")
+	}
+	if Verify("secret", 1760000001, body, sig) {
+		t.Fatal("verified with the wrong timestamp")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	cases := map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		5:  8 * time.Minute,
+		20: 6 * time.Hour,
+	}
+	for attempt, want := range cases {
+		if got := Backoff(attempt); got != want {
+			t.Errorf("Backoff(%d) = %s, want %s", attempt, got, want)
+		}
+	}
+}
+
+func TestParseEvents(t *testing.T) {
+	filter, unknown := ParseEvents(" Room.Created, meeting.ended,room.created,nope")
+	if filter != "room.created,meeting.ended" {
+		t.Fatalf("filter = %q", filter)
+	}
+	if len(unknown) != 1 || unknown[0] != "nope" {
+		t.Fatalf("unknown = %v", unknown)
+	}
+	if !Matches(filter, MeetingEnded) || Matches(filter, RoomDeleted) {
+		t.Fatal("Matches disagrees with the filter")
+	}
+	if all, _ := ParseEvents(""); !Matches(all, ParticipantLeft) {
+		t.Fatal("empty filter should match everything")
+	}
+}